@@ -27,18 +27,26 @@ func analyzeCmd() *cobra.Command {
 	flags := &flags{}
 
 	cmd := &cobra.Command{
-		Use:   "analyze <trace-id>",
+		Use:   "analyze <trace-id|trace-url>",
 		Short: "Analyze telemetry data using LLM",
-		Args:  cobra.ExactArgs(1),
+		Long: "Analyze telemetry data using LLM.\n\n" +
+			"The trace can be given as a trace ID or as a URL of New Relic distributed tracing, " +
+			"GCP Trace explorer, Jaeger UI or Grafana Explore. The time window in the URL is used " +
+			"unless --start-time is given.",
+		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			// The duration only applies to the given start time, not to the time range in the URL or the located one
+			if cmd.Flags().Changed("duration") && flags.startTime == "" {
+				return errors.New("--duration requires --start-time")
+			}
 			return runAnalyze(flags, args)
 		},
 	}
 
 	cmd.Flags().StringVarP(&flags.analysisType, "type", "t", "", "[required] Analysis type (duration, error)")
 	cmd.Flags().StringVarP(&flags.configPath, "config", "c", "", "[required] Config path")
-	cmd.Flags().StringVarP(&flags.startTime, "start-time", "s", "", "[required] Start time for telemetry data (e.g., '2025-01-12 12:00:00)")
-	cmd.Flags().DurationVarP(&flags.duration, "duration", "d", 30*time.Minute, "[required] Duration from start time for telemetry data")
+	cmd.Flags().StringVarP(&flags.startTime, "start-time", "s", "", "Start time for telemetry data (e.g., '2025-01-12 12:00:00). Taken from the trace URL or located from the trace ID if omitted")
+	cmd.Flags().DurationVarP(&flags.duration, "duration", "d", 30*time.Minute, "Duration from start time for telemetry data (requires --start-time)")
 	cmd.Flags().BoolVarP(&flags.queryOnly, "query-only", "q", false, "Only display the fetched telemetry without executing LLM analysis")
 
 	if err := cmd.MarkFlagRequired("type"); err != nil {
@@ -47,12 +55,6 @@ func analyzeCmd() *cobra.Command {
 	if err := cmd.MarkFlagRequired("config"); err != nil {
 		panic(fmt.Sprintf("Failed to mark config flag as required: %v", err))
 	}
-	if err := cmd.MarkFlagRequired("start-time"); err != nil {
-		panic(fmt.Sprintf("Failed to mark start-time flag as required: %v", err))
-	}
-	if err := cmd.MarkFlagRequired("duration"); err != nil {
-		panic(fmt.Sprintf("Failed to mark duration flag as required: %v", err))
-	}
 
 	return cmd
 }
@@ -61,20 +63,25 @@ func runAnalyze(flags *flags, args []string) error {
 	if flags.analysisType != "duration" && flags.analysisType != "error" {
 		return fmt.Errorf("unsupported analysis type: %s (supported: duration, error)", flags.analysisType)
 	}
-	startTime, err := dateparse.ParseAny(flags.startTime)
-	if err != nil {
-		return fmt.Errorf("failed to parse start time: %w", err)
+
+	// The time range is located from the trace ID when no start time is given
+	var timeRange *backend.TimeRange
+	if flags.startTime != "" {
+		startTime, err := dateparse.ParseAny(flags.startTime)
+		if err != nil {
+			return fmt.Errorf("failed to parse start time: %w", err)
+		}
+		timeRange = &backend.TimeRange{
+			Start: startTime,
+			End:   startTime.Add(flags.duration),
+		}
 	}
-	endTime := startTime.Add(flags.duration)
 
-	traceID := args[0]
+	traceRef := args[0]
 
 	l := logger.NewStdoutLogger()
 
-	app, err := app.NewApp(flags.configPath, l, traceID, &backend.TimeRange{
-		Start: startTime,
-		End:   endTime,
-	})
+	app, err := app.NewApp(flags.configPath, l, traceRef, timeRange)
 	if err != nil {
 		return fmt.Errorf("failed to initialize app: %w", err)
 	}
@@ -85,7 +92,7 @@ func runAnalyze(flags *flags, args []string) error {
 	case "duration":
 		return app.RunDuration(ctx, flags.queryOnly)
 	case "error":
-		return errors.New("error analysis is not yet implemented")
+		return app.RunError(ctx, flags.queryOnly)
 	}
 
 	return nil
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"html"
 	"log"
 	"net/http"
 	"os"
@@ -19,6 +20,11 @@ import (
 
 // Slack bot needs chat:write scope and be added to the channel where it will post messages.
 
+const (
+	analysisTypeDuration = "duration"
+	analysisTypeError    = "error"
+)
+
 func HandleCommand(w http.ResponseWriter, r *http.Request) {
 	slackbotToken := os.Getenv("SLACK_BOT_TOKEN")
 	verificationToken := os.Getenv("SLACK_VERIFICATION_TOKEN")
@@ -60,13 +66,19 @@ func HandleCommand(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		// parse /telemetry-glue analyze <trace-id> <date yyyy/mm/dd> <time HH:MM>
-		// example: /telemetry-glue analyze 1234567890abcdef 2024/05/12 15:10
+		// parse /telemetry-glue analyze <trace-id|trace-url> [<date yyyy/mm/dd> <time HH:MM>] [duration|error]
+		// example: /telemetry-glue analyze 1234567890abcdef 2024/05/12 15:10 error
+		// example: /telemetry-glue analyze 1234567890abcdef
+		// example: /telemetry-glue analyze https://one.newrelic.com/distributed-tracing?... error
 		args := strings.Split(s.Text, " ")
 		if len(args) == 1 {
 			if args[0] == "help" {
-				helpMsg := "使い方: /telemetry-glue analyze <trace-id> <date yyyy/mm/dd> <time HH:MM>\n" +
-					"例: /telemetry-glue analyze 1234567890abcdef 2024/05/12 15:10"
+				helpMsg := "使い方: /telemetry-glue analyze <trace-id|trace-url> [<date yyyy/mm/dd> <time HH:MM>] [duration|error]\n" +
+					"例: /telemetry-glue analyze 1234567890abcdef 2024/05/12 15:10 error\n" +
+					"例: /telemetry-glue analyze 1234567890abcdef\n" +
+					"例: /telemetry-glue analyze https://one.newrelic.com/distributed-tracing?... error\n" +
+					"トレースIDの代わりにNew Relic、GCP Trace、Jaeger、GrafanaのURLも貼り付けられます。\n" +
+					"日時を省略した場合はURLの時間範囲を使うか、トレースIDから時間範囲を自動で探します。分析タイプを省略した場合はdurationになります。"
 				_, _, err := slackClient.PostMessage(
 					s.ChannelID,
 					slack.MsgOptionText(helpMsg, false),
@@ -79,12 +91,28 @@ func HandleCommand(w http.ResponseWriter, r *http.Request) {
 			}
 			return
 		}
-		if len(args) != 4 || args[0] != "analyze" {
+		if len(args) < 2 || len(args) > 5 || args[0] != "analyze" {
 			log.Printf("Invalid command format: %s", s.Text)
 			http.Error(w, "使い方が違うみたい。/telemetry-glue helpを確認してね", http.StatusBadRequest)
 			return
 		}
-		traceID := args[1]
+		traceID := unwrapSlackLink(args[1])
+		timestamp := ""
+		analysisType := analysisTypeDuration
+		switch len(args) {
+		case 3:
+			analysisType = args[2]
+		case 4:
+			timestamp = args[2] + " " + args[3]
+		case 5:
+			timestamp = args[2] + " " + args[3]
+			analysisType = args[4]
+		}
+		if analysisType != analysisTypeDuration && analysisType != analysisTypeError {
+			log.Printf("Invalid analysis type: %s", analysisType)
+			http.Error(w, "分析タイプはdurationかerrorを指定してね", http.StatusBadRequest)
+			return
+		}
 
 		log.Printf("channel_id: %s, thread_ts: %s, trace_id: %s", s.ChannelID, ts, traceID)
 
@@ -92,10 +120,11 @@ func HandleCommand(w http.ResponseWriter, r *http.Request) {
 		result := publisher.Publish(ctx, &pubsub.Message{
 			Data: []byte(s.Text),
 			Attributes: map[string]string{
-				"channel_id": s.ChannelID,
-				"thread_ts":  ts,
-				"trace_id":   traceID,
-				"timestamp":  args[2] + " " + args[3],
+				"channel_id":    s.ChannelID,
+				"thread_ts":     ts,
+				"trace_id":      traceID,
+				"timestamp":     timestamp,
+				"analysis_type": analysisType,
 			},
 		})
 		_, err = result.Get(ctx)
@@ -112,6 +141,16 @@ func HandleCommand(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// unwrapSlackLink returns the URL of a link formatted by Slack (e.g., <https://example.com?a=1&amp;b=2|label>)
+// and returns other text as is
+func unwrapSlackLink(s string) string {
+	if !strings.HasPrefix(s, "<") || !strings.HasSuffix(s, ">") {
+		return s
+	}
+	link, _, _ := strings.Cut(strings.TrimSuffix(strings.TrimPrefix(s, "<"), ">"), "|")
+	return html.UnescapeString(link)
+}
+
 func HandlePubsub(ctx context.Context, m *pubsub.Message) error {
 	slackbotToken := os.Getenv("SLACK_BOT_TOKEN")
 	if slackbotToken == "" {
@@ -121,34 +160,43 @@ func HandlePubsub(ctx context.Context, m *pubsub.Message) error {
 	channelID := m.Attributes["channel_id"]
 	threadTS := m.Attributes["thread_ts"]
 	traceID := m.Attributes["trace_id"]
+	// timestamp is empty when the time range should be located from the trace ID
 	timestamp := m.Attributes["timestamp"]
-	if channelID == "" || threadTS == "" || traceID == "" || timestamp == "" {
-		return errors.New("missing channel_id or thread_ts or trace_id or timestamp in message attributes")
+	if channelID == "" || threadTS == "" || traceID == "" {
+		return errors.New("missing channel_id or thread_ts or trace_id in message attributes")
 	}
 
 	client := slack.New(slackbotToken)
 	logger := logger.NewSlackLogger(client, channelID, threadTS)
 
-	jst, err := time.LoadLocation("Asia/Tokyo")
-	if err != nil {
-		return fmt.Errorf("failed to load JST location: %w", err)
-	}
-	start, err := time.ParseInLocation("2006/01/02 15:04", timestamp, jst)
-	if err != nil {
-		logger.Log("日付の解析に失敗しました。フォーマットはyyyy/mm/dd HH:MMで指定してください。")
-		return fmt.Errorf("failed to parse timestamp: %w", err)
-	}
+	var timeRange *backend.TimeRange
+	if timestamp != "" {
+		jst, err := time.LoadLocation("Asia/Tokyo")
+		if err != nil {
+			return fmt.Errorf("failed to load JST location: %w", err)
+		}
+		start, err := time.ParseInLocation("2006/01/02 15:04", timestamp, jst)
+		if err != nil {
+			logger.Log("日付の解析に失敗しました。フォーマットはyyyy/mm/dd HH:MMで指定してください。")
+			return fmt.Errorf("failed to parse timestamp: %w", err)
+		}
 
-	timeRange := backend.TimeRange{
-		Start: start,
-		End:   start.Add(30 * time.Minute),
+		timeRange = &backend.TimeRange{
+			Start: start,
+			End:   start.Add(30 * time.Minute),
+		}
 	}
 
-	app, err := app.NewApp("", logger, traceID, &timeRange)
+	app, err := app.NewApp("", logger, traceID, timeRange)
 	if err != nil {
 		logger.Log("Appの初期化に失敗しました。")
 		return fmt.Errorf("failed to initialize app: %w", err)
 	}
 
-	return app.RunDuration(context.Background(), false)
+	switch m.Attributes["analysis_type"] {
+	case analysisTypeError:
+		return app.RunError(context.Background(), false)
+	default:
+		return app.RunDuration(context.Background(), false)
+	}
 }
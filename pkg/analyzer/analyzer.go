@@ -2,9 +2,12 @@ package analyzer
 
 import (
 	"context"
+	"io"
+	"time"
 
 	"github.com/ymtdzzz/telemetry-glue/pkg/analyzer/backend"
 	"github.com/ymtdzzz/telemetry-glue/pkg/app/config"
+	"github.com/ymtdzzz/telemetry-glue/pkg/app/logger"
 	"github.com/ymtdzzz/telemetry-glue/pkg/app/model"
 )
 
@@ -16,29 +19,85 @@ const (
 	AnalysisTypeError    AnalysisType = "error"
 )
 
+// defaultRequestTimeout bounds each request to the LLM backend unless a timeout is configured
+const defaultRequestTimeout = 5 * time.Minute
+
+// reportGenerationOptions are the default generation options of the reports of each analysis type.
+// The temperature is kept low so that the reports stick to the telemetry and are stable across runs,
+// and the error analysis gets the lowest as its root cause should be derived strictly from the data.
+var reportGenerationOptions = map[AnalysisType]backend.GenerationOptions{
+	AnalysisTypeDuration: {Temperature: float64Ptr(0.2), Timeout: defaultRequestTimeout},
+	AnalysisTypeError:    {Temperature: float64Ptr(0), Timeout: defaultRequestTimeout},
+}
+
+// summaryGenerationOptions are the default generation options of the chunk summaries,
+// whose length is bounded so that the summaries of all the chunks fit in the synthesis prompt
+var summaryGenerationOptions = backend.GenerationOptions{
+	Temperature: float64Ptr(0),
+	MaxTokens:   2048,
+	Timeout:     defaultRequestTimeout,
+}
+
 // Analyzer struct that uses an LLMBackend to analyze telemetry data
 type Analyzer struct {
-	backend  *backend.LLMBackend
-	language string
+	backend        *backend.LLMBackend
+	logger         logger.Loggable
+	language       string
+	maxInputTokens int
 }
 
-// NewAnalyzer creates a new Analyzer instance
-func NewAnalyzer(config *config.AnalyzerConfig) (*Analyzer, error) {
-	backend, err := backend.NewLLMBackend(config)
+// NewAnalyzer creates a new Analyzer instance. The logger receives the progress of
+// analyses split into chunks.
+func NewAnalyzer(config *config.AnalyzerConfig, logger logger.Loggable) (*Analyzer, error) {
+	backend, err := backend.NewLLMBackend(config, logger)
 	if err != nil {
 		return nil, err
 	}
 	return &Analyzer{
-		backend:  &backend,
-		language: config.Language,
+		backend:        &backend,
+		logger:         logger,
+		language:       config.Language,
+		maxInputTokens: config.MaxInputTokens(),
 	}, nil
 }
 
-// AnalyzeDuration generates a report based on the provided telemetry data and prompt
-func (a *Analyzer) AnalyzeDuration(ctx context.Context, telemetry *model.Telemetry) (string, error) {
-	content, err := generatePrompt(AnalysisTypeDuration, telemetry, a.language)
+// AnalyzeDuration generates a report based on the provided telemetry data and prompt.
+// When stream is not nil, the report is also written to it as it is generated.
+func (a *Analyzer) AnalyzeDuration(ctx context.Context, telemetry *model.Telemetry, stream io.Writer) (string, error) {
+	return a.analyze(ctx, AnalysisTypeDuration, telemetry, stream)
+}
+
+// AnalyzeError generates an error analysis report based on the provided telemetry data.
+// When stream is not nil, the report is also written to it as it is generated.
+func (a *Analyzer) AnalyzeError(ctx context.Context, telemetry *model.Telemetry, stream io.Writer) (string, error) {
+	return a.analyze(ctx, AnalysisTypeError, telemetry, stream)
+}
+
+// analyze generates the report with a single prompt when the telemetry fits in the token budget
+// without dropping spans or logs, and analyzes it in chunks otherwise
+func (a *Analyzer) analyze(ctx context.Context, analysisType AnalysisType, telemetry *model.Telemetry, stream io.Writer) (string, error) {
+	reduced, fits, err := telemetry.Reduce(a.maxInputTokens - promptOverheadTokens)
 	if err != nil {
 		return "", err
 	}
-	return (*a.backend).GenerateReport(ctx, content)
+	if !fits {
+		return a.analyzeInChunks(ctx, analysisType, telemetry, stream)
+	}
+
+	content, err := generatePrompt(analysisType, telemetry, reduced, a.language)
+	if err != nil {
+		return "", err
+	}
+	return (*a.backend).GenerateReport(ctx, content, reportOptions(analysisType, stream))
+}
+
+// reportOptions returns the default generation options of the report streamed to the writer
+func reportOptions(analysisType AnalysisType, stream io.Writer) backend.GenerationOptions {
+	options := reportGenerationOptions[analysisType]
+	options.Stream = stream
+	return options
+}
+
+func float64Ptr(v float64) *float64 {
+	return &v
 }
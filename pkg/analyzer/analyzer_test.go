@@ -0,0 +1,37 @@
+package analyzer
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReportOptions(t *testing.T) {
+	tests := []struct {
+		analysisType    AnalysisType
+		wantTemperature float64
+	}{
+		{analysisType: AnalysisTypeDuration, wantTemperature: 0.2},
+		{analysisType: AnalysisTypeError, wantTemperature: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.analysisType), func(t *testing.T) {
+			var stream strings.Builder
+			got := reportOptions(tt.analysisType, &stream)
+
+			if got.Temperature == nil || *got.Temperature != tt.wantTemperature {
+				t.Errorf("Temperature = %v, want %v", got.Temperature, tt.wantTemperature)
+			}
+			if got.Timeout != 5*time.Minute {
+				t.Errorf("Timeout = %v, want 5m", got.Timeout)
+			}
+			if got.MaxTokens != 0 {
+				t.Errorf("MaxTokens = %d, want 0 to leave it to the backend", got.MaxTokens)
+			}
+			if got.Stream != &stream {
+				t.Error("Stream is not the given writer")
+			}
+		})
+	}
+}
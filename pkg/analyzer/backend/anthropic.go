@@ -0,0 +1,45 @@
+package backend
+
+import (
+	"context"
+
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/llms/anthropic"
+	"github.com/ymtdzzz/telemetry-glue/pkg/app/config"
+)
+
+type Anthropic struct {
+	llm       *anthropic.LLM
+	modelName string
+	options   GenerationOptions
+}
+
+func NewAnthropic(config *config.AnthropicConfig) (*Anthropic, error) {
+	opts := []anthropic.Option{
+		anthropic.WithModel(config.ModelName),
+		anthropic.WithToken(config.APIKey),
+	}
+	if config.BaseURL != "" {
+		opts = append(opts, anthropic.WithBaseURL(config.BaseURL))
+	}
+
+	llm, err := anthropic.New(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Anthropic{
+		llm:       llm,
+		modelName: config.ModelName,
+		options:   newGenerationOptions(&config.GenerationConfig),
+	}, nil
+}
+
+func (a *Anthropic) GenerateReport(
+	ctx context.Context,
+	content []llms.MessageContent,
+	defaults GenerationOptions,
+) (string, error) {
+	options := a.options.withDefaults(defaults).withRequiredMaxTokens()
+	return getGeneratedContent(ctx, a.llm, content, options)
+}
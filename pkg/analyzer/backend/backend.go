@@ -3,10 +3,13 @@ package backend
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io"
 	"strings"
 
 	"github.com/tmc/langchaingo/llms"
 	"github.com/ymtdzzz/telemetry-glue/pkg/app/config"
+	"github.com/ymtdzzz/telemetry-glue/pkg/app/logger"
 )
 
 // AnalysisType represents the type of analysis to perform
@@ -17,40 +20,103 @@ const (
 	AnalysisTypeError    AnalysisType = "error"
 )
 
+// defaultMaxTokens is the maximum number of tokens to generate for the backends that require one
+const defaultMaxTokens = 4096
+
 // LLMBackend defines the interface for telemetry analyzers
 type LLMBackend interface {
+	// GenerateReport generates the report from the content. The defaults are used for
+	// the generation options not set in the backend configuration.
 	GenerateReport(
 		ctx context.Context,
 		content []llms.MessageContent,
+		defaults GenerationOptions,
 	) (string, error)
 }
 
-// NewLLMBackend creates a new LLMBackend based on the provided configuration
-func NewLLMBackend(cfg *config.AnalyzerConfig) (LLMBackend, error) {
-	if cfg.Ollama.HasAnyConfig() {
+// llmBackendFactory builds an LLMBackend from the analyzer configuration
+type llmBackendFactory func(ctx context.Context, cfg *config.AnalyzerConfig) (LLMBackend, error)
+
+// llmBackendFactories holds the factory for each supported LLM backend type
+var llmBackendFactories = map[config.LLMBackendType]llmBackendFactory{
+	config.LLMBackendTypeOllama: func(_ context.Context, cfg *config.AnalyzerConfig) (LLMBackend, error) {
 		return NewOllama(&cfg.Ollama)
+	},
+	config.LLMBackendTypeGemini: func(ctx context.Context, cfg *config.AnalyzerConfig) (LLMBackend, error) {
+		return NewGemini(ctx, &cfg.Gemini)
+	},
+	config.LLMBackendTypeVertexAI: func(ctx context.Context, cfg *config.AnalyzerConfig) (LLMBackend, error) {
+		return NewVertexAI(ctx, &cfg.VertexAI)
+	},
+	config.LLMBackendTypeOpenAI: func(_ context.Context, cfg *config.AnalyzerConfig) (LLMBackend, error) {
+		return NewOpenAI(&cfg.OpenAI)
+	},
+	config.LLMBackendTypeAnthropic: func(_ context.Context, cfg *config.AnalyzerConfig) (LLMBackend, error) {
+		return NewAnthropic(&cfg.Anthropic)
+	},
+	config.LLMBackendTypeBedrock: func(ctx context.Context, cfg *config.AnalyzerConfig) (LLMBackend, error) {
+		return NewBedrock(ctx, &cfg.Bedrock)
+	},
+}
+
+// NewLLMBackend creates the selected LLMBackend based on the provided configuration.
+// When fallbacks are configured, the returned backend tries them in order and
+// reports each fallback to the logger.
+func NewLLMBackend(cfg *config.AnalyzerConfig, logger logger.Loggable) (LLMBackend, error) {
+	chain := cfg.BackendChain()
+	if len(chain) == 0 {
+		return nil, errors.New("no valid LLM backend configuration found")
 	}
-	if cfg.Gemini.HasAnyConfig() {
-		return NewGemini(context.Background(), &cfg.Gemini)
+
+	backends := make([]namedBackend, 0, len(chain))
+	for _, t := range chain {
+		factory, ok := llmBackendFactories[t]
+		if !ok {
+			return nil, fmt.Errorf("unsupported LLM backend type: %s", t)
+		}
+		b, err := factory(context.Background(), cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create %s LLM backend: %w", t, err)
+		}
+		backends = append(backends, namedBackend{name: t, backend: b})
 	}
-	if cfg.VertexAI.HasAnyConfig() {
-		return NewVertexAI(context.Background(), &cfg.VertexAI)
+
+	if len(backends) == 1 {
+		return backends[0].backend, nil
 	}
-	return nil, errors.New("no valid LLM backend configuration found")
+	return newFallbackBackend(backends, logger), nil
 }
 
+// getGeneratedContent generates the content with streaming, and writes the chunks to
+// the stream of the options as they arrive when it is set. Models that do not support
+// streaming (e.g., most Bedrock providers) return the whole content in the response instead.
 func getGeneratedContent(
 	ctx context.Context,
 	llm llms.Model,
 	content []llms.MessageContent,
+	generationOptions GenerationOptions,
 ) (string, error) {
+	if generationOptions.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, generationOptions.Timeout)
+		defer cancel()
+	}
+	// The generation is canceled when the stream cannot be written
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	options := generationOptions.callOptions()
 	chunks := make(chan string)
-	errChan := make(chan error, 1)
+	type generated struct {
+		resp *llms.ContentResponse
+		err  error
+	}
+	done := make(chan generated, 1)
 	var result strings.Builder
 
 	go func() {
 		defer close(chunks)
-		_, err := llm.GenerateContent(ctx, content, llms.WithStreamingFunc(func(ctx context.Context, chunk []byte) error {
+		options = append(options, llms.WithStreamingFunc(func(ctx context.Context, chunk []byte) error {
 			select {
 			case chunks <- string(chunk):
 			case <-ctx.Done():
@@ -58,15 +124,38 @@ func getGeneratedContent(
 			}
 			return nil
 		}))
-		errChan <- err
+		resp, err := llm.GenerateContent(ctx, content, options...)
+		done <- generated{resp: resp, err: err}
 	}()
 
+	var streamErr error
 	for chunk := range chunks {
 		result.WriteString(chunk)
+		if generationOptions.Stream != nil && streamErr == nil {
+			if _, err := io.WriteString(generationOptions.Stream, chunk); err != nil {
+				streamErr = err
+				cancel()
+			}
+		}
 	}
 
-	if err := <-errChan; err != nil {
-		return "", err
+	if streamErr != nil {
+		return "", fmt.Errorf("failed to stream the generated content: %w", streamErr)
+	}
+	g := <-done
+	if g.err != nil {
+		return "", g.err
+	}
+
+	if result.Len() == 0 && g.resp != nil && len(g.resp.Choices) > 0 {
+		// No chunk was streamed, so the content is only in the response
+		text := g.resp.Choices[0].Content
+		if generationOptions.Stream != nil {
+			if _, err := io.WriteString(generationOptions.Stream, text); err != nil {
+				return "", fmt.Errorf("failed to stream the generated content: %w", err)
+			}
+		}
+		return text, nil
 	}
 
 	return result.String(), nil
@@ -0,0 +1,65 @@
+package backend
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// stubModel returns the chunks through the streaming function when streaming is true,
+// and the whole content in the response only otherwise
+type stubModel struct {
+	chunks    []string
+	streaming bool
+}
+
+func (m *stubModel) GenerateContent(ctx context.Context, _ []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	opts := llms.CallOptions{}
+	for _, opt := range options {
+		opt(&opts)
+	}
+	if m.streaming && opts.StreamingFunc != nil {
+		for _, c := range m.chunks {
+			if err := opts.StreamingFunc(ctx, []byte(c)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &llms.ContentResponse{
+		Choices: []*llms.ContentChoice{{Content: strings.Join(m.chunks, "")}},
+	}, nil
+}
+
+func (m *stubModel) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, m, prompt, options...)
+}
+
+func TestGetGeneratedContent(t *testing.T) {
+	tests := []struct {
+		name      string
+		streaming bool
+	}{
+		{name: "streaming model", streaming: true},
+		{name: "non-streaming model", streaming: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			model := &stubModel{chunks: []string{"Hello, ", "world"}, streaming: tt.streaming}
+			var stream strings.Builder
+
+			got, err := getGeneratedContent(context.Background(), model, nil, GenerationOptions{Stream: &stream})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != "Hello, world" {
+				t.Errorf("got %q, want %q", got, "Hello, world")
+			}
+			if stream.String() != "Hello, world" {
+				t.Errorf("streamed %q, want %q", stream.String(), "Hello, world")
+			}
+		})
+	}
+}
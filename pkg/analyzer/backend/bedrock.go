@@ -0,0 +1,59 @@
+package backend
+
+import (
+	"context"
+
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/llms/bedrock"
+	"github.com/ymtdzzz/telemetry-glue/pkg/app/config"
+)
+
+type Bedrock struct {
+	llm       *bedrock.LLM
+	modelName string
+	options   GenerationOptions
+}
+
+// NewBedrock creates a new Bedrock backend using the default AWS credential chain
+func NewBedrock(ctx context.Context, config *config.BedrockConfig) (*Bedrock, error) {
+	loadOpts := []func(*awsconfig.LoadOptions) error{
+		awsconfig.WithRegion(config.Region),
+	}
+	if config.Profile != "" {
+		loadOpts = append(loadOpts, awsconfig.WithSharedConfigProfile(config.Profile))
+	}
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx, loadOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []bedrock.Option{
+		bedrock.WithClient(bedrockruntime.NewFromConfig(awsCfg)),
+		bedrock.WithModel(config.ModelName),
+	}
+	if config.ModelProvider != "" {
+		opts = append(opts, bedrock.WithModelProvider(config.ModelProvider))
+	}
+
+	llm, err := bedrock.New(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Bedrock{
+		llm:       llm,
+		modelName: config.ModelName,
+		options:   newGenerationOptions(&config.GenerationConfig),
+	}, nil
+}
+
+func (b *Bedrock) GenerateReport(
+	ctx context.Context,
+	content []llms.MessageContent,
+	defaults GenerationOptions,
+) (string, error) {
+	options := b.options.withDefaults(defaults).withRequiredMaxTokens()
+	return getGeneratedContent(ctx, b.llm, content, options)
+}
@@ -0,0 +1,152 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"github.com/tmc/langchaingo/llms"
+	"github.com/ymtdzzz/telemetry-glue/pkg/app/config"
+	"github.com/ymtdzzz/telemetry-glue/pkg/app/logger"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type namedBackend struct {
+	name    config.LLMBackendType
+	backend LLMBackend
+}
+
+// fallbackBackend generates the report with the first backend, and retries on the next ones
+// when it fails with an error that another provider may not have
+type fallbackBackend struct {
+	backends []namedBackend
+	logger   logger.Loggable
+}
+
+func newFallbackBackend(backends []namedBackend, logger logger.Loggable) *fallbackBackend {
+	return &fallbackBackend{
+		backends: backends,
+		logger:   logger,
+	}
+}
+
+func (f *fallbackBackend) GenerateReport(
+	ctx context.Context,
+	content []llms.MessageContent,
+	defaults GenerationOptions,
+) (string, error) {
+	var stream *countingWriter
+	if defaults.Stream != nil {
+		stream = &countingWriter{w: defaults.Stream}
+		defaults.Stream = stream
+	}
+
+	var errs []error
+	for i, b := range f.backends {
+		report, err := b.backend.GenerateReport(ctx, content, defaults)
+		if err == nil {
+			return report, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", b.name, err))
+
+		if i == len(f.backends)-1 || !shouldFallback(ctx, err) {
+			break
+		}
+		if stream != nil && stream.n > 0 {
+			// The partial output already streamed cannot be taken back
+			break
+		}
+		next := f.backends[i+1].name
+		if lerr := f.logger.Log(fmt.Sprintf("The %s LLM backend failed (%v); falling back to %s...", b.name, err, next)); lerr != nil {
+			return "", lerr
+		}
+	}
+	return "", errors.Join(errs...)
+}
+
+// shouldFallback reports whether the error is worth retrying on another backend: quota and
+// rate limits, server errors, timeouts and errors that cannot be classified such as network
+// errors. Invalid requests and filtered content would fail on any backend.
+func shouldFallback(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		// The analysis itself was canceled or timed out
+		return false
+	}
+
+	if code, ok := grpcCode(err); ok {
+		switch code {
+		case codes.ResourceExhausted, codes.Unavailable, codes.DeadlineExceeded, codes.Internal, codes.Unknown, codes.Aborted:
+			return true
+		}
+		return false
+	}
+	if status, ok := httpStatus(err); ok {
+		return status == http.StatusPaymentRequired ||
+			status == http.StatusRequestTimeout ||
+			status == http.StatusTooManyRequests ||
+			status >= http.StatusInternalServerError
+	}
+
+	// Without a status, only filtered content is recognized from the message, as the error mapper
+	// also matches substrings such as "400" or "api key" that may appear in a quota error
+	var llmErr *llms.Error
+	if errors.As(llms.NewErrorMapper("").WrapError(err), &llmErr) && llmErr.Code == llms.ErrCodeContentFilter {
+		return false
+	}
+	return true
+}
+
+// grpcCode returns the gRPC status code of the error returned by the Gemini and Vertex AI clients
+func grpcCode(err error) (codes.Code, bool) {
+	var grpcErr interface{ GRPCStatus() *status.Status }
+	if !errors.As(err, &grpcErr) || grpcErr.GRPCStatus() == nil {
+		return codes.OK, false
+	}
+	return grpcErr.GRPCStatus().Code(), true
+}
+
+// httpStatusPattern matches the response status in the errors of the OpenAI and Anthropic clients
+// ("API returned unexpected status code: 429: ...") and of Ollama ("503 Service Unavailable: ...")
+var httpStatusPattern = regexp.MustCompile(`status code: ([1-5][0-9][0-9])\b|^([1-5][0-9][0-9]) [A-Z]`)
+
+// httpStatus returns the HTTP response status of the error returned by the LLM backend
+func httpStatus(err error) (int, bool) {
+	// Bedrock (AWS SDK)
+	var awsErr interface{ HTTPStatusCode() int }
+	if errors.As(err, &awsErr) {
+		return awsErr.HTTPStatusCode(), true
+	}
+	// Google APIs over REST
+	var googleErr *googleapi.Error
+	if errors.As(err, &googleErr) {
+		return googleErr.Code, true
+	}
+	var apiErr interface{ HTTPCode() int }
+	if errors.As(err, &apiErr) && apiErr.HTTPCode() > 0 {
+		return apiErr.HTTPCode(), true
+	}
+
+	if m := httpStatusPattern.FindStringSubmatch(err.Error()); m != nil {
+		status, _ := strconv.Atoi(m[1] + m[2])
+		return status, true
+	}
+	return 0, false
+}
+
+// countingWriter counts the bytes written to the underlying writer
+type countingWriter struct {
+	w io.Writer
+	n int
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += n
+	return n, err
+}
@@ -0,0 +1,128 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// awsError builds the error returned by the AWS SDK for the HTTP status
+func awsError(statusCode int, message string) error {
+	return &smithyhttp.ResponseError{
+		Response: &smithyhttp.Response{Response: &http.Response{StatusCode: statusCode}},
+		Err:      errors.New(message),
+	}
+}
+
+func TestShouldFallback(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want bool
+	}{
+		{
+			name: "gRPC quota exceeded mentioning an API key",
+			err:  fmt.Errorf("generation failed: %w", status.Error(codes.ResourceExhausted, "quota exceeded for api key, 400 requests per minute")),
+			want: true,
+		},
+		{
+			name: "gRPC unavailable",
+			err:  status.Error(codes.Unavailable, "the service is currently unavailable"),
+			want: true,
+		},
+		{
+			name: "gRPC invalid argument",
+			err:  status.Error(codes.InvalidArgument, "request contains an invalid argument"),
+			want: false,
+		},
+		{
+			name: "Google API rate limit over REST",
+			err:  &googleapi.Error{Code: http.StatusTooManyRequests, Message: "resource has been exhausted (check quota)"},
+			want: true,
+		},
+		{
+			name: "Google API not found over REST",
+			err:  &googleapi.Error{Code: http.StatusNotFound, Message: "model not found"},
+			want: false,
+		},
+		{
+			name: "Bedrock throttling mentioning 404",
+			err:  awsError(http.StatusTooManyRequests, "ThrottlingException: too many requests, retry in 404ms"),
+			want: true,
+		},
+		{
+			name: "Bedrock validation error",
+			err:  awsError(http.StatusBadRequest, "ValidationException: malformed input request"),
+			want: false,
+		},
+		{
+			name: "Bedrock server error",
+			err:  awsError(http.StatusInternalServerError, "InternalServerException"),
+			want: true,
+		},
+		{
+			name: "OpenAI quota mentioning an API key",
+			err:  errors.New("API returned unexpected status code: 429: You exceeded your current quota for this api key"),
+			want: true,
+		},
+		{
+			name: "OpenAI invalid request",
+			err:  errors.New("API returned unexpected status code: 400: Invalid value for 'messages'"),
+			want: false,
+		},
+		{
+			name: "Anthropic overloaded",
+			err:  errors.New("API returned unexpected status code: 529: Overloaded"),
+			want: true,
+		},
+		{
+			name: "Ollama server error",
+			err:  errors.New("503 Service Unavailable: server busy"),
+			want: true,
+		},
+		{
+			name: "message starting with a number",
+			err:  errors.New("400 requests per minute exceeded"),
+			want: true,
+		},
+		{
+			name: "content filter without a status",
+			err:  errors.New("the response was blocked by the content filter"),
+			want: false,
+		},
+		{
+			name: "network error",
+			err:  errors.New("dial tcp 127.0.0.1:11434: connect: connection refused"),
+			want: true,
+		},
+		{
+			name: "canceled analysis",
+			ctx:  canceled,
+			err:  status.Error(codes.Unavailable, "the service is currently unavailable"),
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := tt.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			if got := shouldFallback(ctx, tt.err); got != tt.want {
+				t.Errorf("shouldFallback(%q) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
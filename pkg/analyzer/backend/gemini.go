@@ -11,6 +11,7 @@ import (
 type Gemini struct {
 	llm       *googleai.GoogleAI
 	modelName string
+	options   GenerationOptions
 }
 
 func NewGemini(ctx context.Context, config *config.GeminiConfig) (*Gemini, error) {
@@ -22,12 +23,14 @@ func NewGemini(ctx context.Context, config *config.GeminiConfig) (*Gemini, error
 	return &Gemini{
 		llm:       llm,
 		modelName: config.ModelName,
+		options:   newGenerationOptions(&config.GenerationConfig),
 	}, nil
 }
 
 func (g *Gemini) GenerateReport(
 	ctx context.Context,
 	content []llms.MessageContent,
+	defaults GenerationOptions,
 ) (string, error) {
-	return getGeneratedContent(ctx, g.llm, content)
+	return getGeneratedContent(ctx, g.llm, content, g.options.withDefaults(defaults))
 }
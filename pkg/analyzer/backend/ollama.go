@@ -11,10 +11,18 @@ import (
 type Ollama struct {
 	llm       *ollama.LLM
 	modelName string
+	options   GenerationOptions
 }
 
 func NewOllama(config *config.OllamaConfig) (*Ollama, error) {
-	llm, err := ollama.New(ollama.WithModel(config.ModelName))
+	opts := []ollama.Option{
+		ollama.WithModel(config.ModelName),
+	}
+	if config.ServerURL != "" {
+		opts = append(opts, ollama.WithServerURL(config.ServerURL))
+	}
+
+	llm, err := ollama.New(opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -22,12 +30,14 @@ func NewOllama(config *config.OllamaConfig) (*Ollama, error) {
 	return &Ollama{
 		llm:       llm,
 		modelName: config.ModelName,
+		options:   newGenerationOptions(&config.GenerationConfig),
 	}, nil
 }
 
 func (o *Ollama) GenerateReport(
 	ctx context.Context,
 	content []llms.MessageContent,
+	defaults GenerationOptions,
 ) (string, error) {
-	return getGeneratedContent(ctx, o.llm, content)
+	return getGeneratedContent(ctx, o.llm, content, o.options.withDefaults(defaults))
 }
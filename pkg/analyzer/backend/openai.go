@@ -0,0 +1,71 @@
+package backend
+
+import (
+	"context"
+
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/llms/openai"
+	"github.com/ymtdzzz/telemetry-glue/pkg/app/config"
+)
+
+// placeholderAPIKey is sent to OpenAI-compatible servers that do not require an API key,
+// as the client refuses to start without one
+const placeholderAPIKey = "unused"
+
+type OpenAI struct {
+	llm       *openai.LLM
+	modelName string
+	options   GenerationOptions
+}
+
+func NewOpenAI(config *config.OpenAIConfig) (*OpenAI, error) {
+	apiKey := config.APIKey
+	if apiKey == "" {
+		apiKey = placeholderAPIKey
+	}
+
+	opts := []openai.Option{
+		openai.WithModel(config.ModelName),
+		openai.WithToken(apiKey),
+	}
+	if config.BaseURL != "" {
+		opts = append(opts, openai.WithBaseURL(config.BaseURL))
+	}
+	if config.Organization != "" {
+		opts = append(opts, openai.WithOrganization(config.Organization))
+	}
+	if apiType := openAIAPIType(config.APIType); apiType != openai.APITypeOpenAI {
+		// Azure OpenAI uses the model name as the deployment name
+		opts = append(opts, openai.WithAPIType(apiType), openai.WithAPIVersion(config.APIVersion))
+	}
+
+	llm, err := openai.New(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OpenAI{
+		llm:       llm,
+		modelName: config.ModelName,
+		options:   newGenerationOptions(&config.GenerationConfig),
+	}, nil
+}
+
+func (o *OpenAI) GenerateReport(
+	ctx context.Context,
+	content []llms.MessageContent,
+	defaults GenerationOptions,
+) (string, error) {
+	return getGeneratedContent(ctx, o.llm, content, o.options.withDefaults(defaults))
+}
+
+func openAIAPIType(apiType string) openai.APIType {
+	switch config.OpenAIAPIType(apiType) {
+	case config.OpenAIAPITypeAzure:
+		return openai.APITypeAzure
+	case config.OpenAIAPITypeAzureAD:
+		return openai.APITypeAzureAD
+	default:
+		return openai.APITypeOpenAI
+	}
+}
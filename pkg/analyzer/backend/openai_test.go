@@ -0,0 +1,155 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tmc/langchaingo/llms"
+	"github.com/ymtdzzz/telemetry-glue/pkg/app/config"
+)
+
+// openAIStreamBody is a streamed chat completion returning "Hello world"
+const openAIStreamBody = `data: {"id":"1","object":"chat.completion.chunk","model":"test-model","choices":[{"index":0,"delta":{"role":"assistant","content":"Hello"},"finish_reason":null}]}
+
+data: {"id":"1","object":"chat.completion.chunk","model":"test-model","choices":[{"index":0,"delta":{"content":" world"},"finish_reason":null}]}
+
+data: {"id":"1","object":"chat.completion.chunk","model":"test-model","choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}
+
+data: [DONE]
+
+`
+
+func TestOpenAIGenerateReport(t *testing.T) {
+	temperature := 0.1
+
+	tests := []struct {
+		name       string
+		config     config.OpenAIConfig
+		status     int
+		body       string
+		wantPath   string
+		wantHeader map[string]string
+		want       string
+		wantErr    string
+	}{
+		{
+			name:     "OpenAI-compatible server",
+			config:   config.OpenAIConfig{ModelName: "test-model", APIKey: "secret", Organization: "org-1"},
+			status:   http.StatusOK,
+			body:     openAIStreamBody,
+			wantPath: "/chat/completions",
+			wantHeader: map[string]string{
+				"Authorization":       "Bearer secret",
+				"OpenAI-Organization": "org-1",
+			},
+			want: "Hello world",
+		},
+		{
+			name:       "server without an API key",
+			config:     config.OpenAIConfig{ModelName: "test-model"},
+			status:     http.StatusOK,
+			body:       openAIStreamBody,
+			wantPath:   "/chat/completions",
+			wantHeader: map[string]string{"Authorization": "Bearer " + placeholderAPIKey},
+			want:       "Hello world",
+		},
+		{
+			name:       "Azure OpenAI",
+			config:     config.OpenAIConfig{ModelName: "test-deployment", APIKey: "secret", APIType: "azure", APIVersion: "2024-10-21"},
+			status:     http.StatusOK,
+			body:       openAIStreamBody,
+			wantPath:   "/openai/deployments/test-deployment/chat/completions",
+			wantHeader: map[string]string{"api-key": "secret"},
+			want:       "Hello world",
+		},
+		{
+			name:     "rate limited",
+			config:   config.OpenAIConfig{ModelName: "test-model", APIKey: "secret"},
+			status:   http.StatusTooManyRequests,
+			body:     `{"error":{"message":"Rate limit reached","type":"requests"}}`,
+			wantPath: "/chat/completions",
+			wantErr:  "API returned unexpected status code: 429: Rate limit reached",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != tt.wantPath {
+					t.Errorf("path = %s, want %s", r.URL.Path, tt.wantPath)
+				}
+				for k, v := range tt.wantHeader {
+					if got := r.Header.Get(k); got != v {
+						t.Errorf("header %s = %q, want %q", k, got, v)
+					}
+				}
+
+				var req struct {
+					Model       string  `json:"model"`
+					Temperature float64 `json:"temperature"`
+					Stream      bool    `json:"stream"`
+					Messages    []struct {
+						Role    string `json:"role"`
+						Content string `json:"content"`
+					} `json:"messages"`
+				}
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+					t.Errorf("failed to decode the request: %v", err)
+				}
+				if req.Model != tt.config.ModelName {
+					t.Errorf("model = %s, want %s", req.Model, tt.config.ModelName)
+				}
+				if req.Temperature != temperature {
+					t.Errorf("temperature = %v, want %v", req.Temperature, temperature)
+				}
+				if !req.Stream {
+					t.Error("the completion should be streamed")
+				}
+				if len(req.Messages) != 2 || req.Messages[1].Content != "Analyze the trace" {
+					t.Errorf("messages = %+v, want the system and human prompts", req.Messages)
+				}
+
+				if tt.status == http.StatusOK {
+					w.Header().Set("Content-Type", "text/event-stream")
+				}
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.body)
+			}))
+			defer server.Close()
+
+			cfg := tt.config
+			cfg.BaseURL = server.URL
+			o, err := NewOpenAI(&cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var stream strings.Builder
+			content := []llms.MessageContent{
+				llms.TextParts(llms.ChatMessageTypeSystem, "You are an expert"),
+				llms.TextParts(llms.ChatMessageTypeHuman, "Analyze the trace"),
+			}
+			got, err := o.GenerateReport(context.Background(), content, GenerationOptions{Temperature: &temperature, Stream: &stream})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("GenerateReport() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("GenerateReport() = %q, want %q", got, tt.want)
+			}
+			if stream.String() != tt.want {
+				t.Errorf("streamed %q, want %q", stream.String(), tt.want)
+			}
+		})
+	}
+}
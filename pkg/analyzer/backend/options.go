@@ -0,0 +1,81 @@
+package backend
+
+import (
+	"io"
+	"time"
+
+	"github.com/tmc/langchaingo/llms"
+	"github.com/ymtdzzz/telemetry-glue/pkg/app/config"
+)
+
+// GenerationOptions controls the text generation. Unset options are left to the model defaults.
+type GenerationOptions struct {
+	Temperature *float64
+	TopP        *float64
+	// Seed is supported by Ollama and OpenAI only
+	Seed      *int
+	MaxTokens int
+	// Timeout is the timeout of each request to the backend
+	Timeout time.Duration
+	// Stream receives the generated text as it arrives when set
+	Stream io.Writer
+}
+
+func newGenerationOptions(cfg *config.GenerationConfig) GenerationOptions {
+	return GenerationOptions{
+		Temperature: cfg.Temperature,
+		TopP:        cfg.TopP,
+		Seed:        cfg.Seed,
+		MaxTokens:   cfg.MaxTokens,
+		Timeout:     cfg.Timeout,
+	}
+}
+
+// withDefaults returns the options with the unset ones taken from the defaults
+func (o GenerationOptions) withDefaults(defaults GenerationOptions) GenerationOptions {
+	if o.Temperature == nil {
+		o.Temperature = defaults.Temperature
+	}
+	if o.TopP == nil {
+		o.TopP = defaults.TopP
+	}
+	if o.Seed == nil {
+		o.Seed = defaults.Seed
+	}
+	if o.MaxTokens == 0 {
+		o.MaxTokens = defaults.MaxTokens
+	}
+	if o.Timeout == 0 {
+		o.Timeout = defaults.Timeout
+	}
+	if o.Stream == nil {
+		o.Stream = defaults.Stream
+	}
+	return o
+}
+
+// withRequiredMaxTokens returns the options with defaultMaxTokens when MaxTokens is unset,
+// for the backends that require the maximum number of tokens to generate
+func (o GenerationOptions) withRequiredMaxTokens() GenerationOptions {
+	if o.MaxTokens == 0 {
+		o.MaxTokens = defaultMaxTokens
+	}
+	return o
+}
+
+func (o GenerationOptions) callOptions() []llms.CallOption {
+	var opts []llms.CallOption
+	if o.Temperature != nil {
+		opts = append(opts, llms.WithTemperature(*o.Temperature))
+	}
+	if o.TopP != nil {
+		opts = append(opts, llms.WithTopP(*o.TopP))
+	}
+	if o.Seed != nil {
+		opts = append(opts, llms.WithSeed(*o.Seed))
+	}
+	if o.MaxTokens > 0 {
+		opts = append(opts, llms.WithMaxTokens(o.MaxTokens))
+	}
+	return opts
+}
@@ -0,0 +1,108 @@
+package backend
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tmc/langchaingo/llms"
+	"github.com/ymtdzzz/telemetry-glue/pkg/app/config"
+)
+
+func TestGenerationOptionsWithDefaults(t *testing.T) {
+	configured, fallback := 0.7, 0.2
+	topP := 0.9
+	seed := 42
+	var stream strings.Builder
+	defaults := GenerationOptions{Temperature: &fallback, MaxTokens: 2048, Timeout: 5 * time.Minute, Stream: &stream}
+
+	tests := []struct {
+		name   string
+		config config.GenerationConfig
+		want   GenerationOptions
+	}{
+		{
+			name: "defaults for unset options",
+			want: defaults,
+		},
+		{
+			name:   "configured options override the defaults",
+			config: config.GenerationConfig{Temperature: &configured, TopP: &topP, Seed: &seed, MaxTokens: 1024, Timeout: time.Minute},
+			want:   GenerationOptions{Temperature: &configured, TopP: &topP, Seed: &seed, MaxTokens: 1024, Timeout: time.Minute, Stream: &stream},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newGenerationOptions(&tt.config).withDefaults(defaults)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("withDefaults() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerationOptionsWithRequiredMaxTokens(t *testing.T) {
+	tests := []struct {
+		name    string
+		options GenerationOptions
+		want    int
+	}{
+		{
+			name: "unset",
+			want: defaultMaxTokens,
+		},
+		{
+			name:    "configured or default of the analysis",
+			options: GenerationOptions{MaxTokens: 2048},
+			want:    2048,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.options.withRequiredMaxTokens().MaxTokens; got != tt.want {
+				t.Errorf("MaxTokens = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerationOptionsCallOptions(t *testing.T) {
+	temperature, topP := 0.0, 0.9
+	seed := 42
+
+	tests := []struct {
+		name    string
+		options GenerationOptions
+		wantLen int
+		want    llms.CallOptions
+	}{
+		{
+			name: "unset options left to the model",
+		},
+		{
+			name:    "set options including a zero temperature",
+			options: GenerationOptions{Temperature: &temperature, TopP: &topP, Seed: &seed, MaxTokens: 1024},
+			wantLen: 4,
+			want:    llms.CallOptions{Temperature: 0, TopP: 0.9, Seed: 42, MaxTokens: 1024},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := tt.options.callOptions()
+			if len(opts) != tt.wantLen {
+				t.Errorf("callOptions() returned %d options, want %d", len(opts), tt.wantLen)
+			}
+			var got llms.CallOptions
+			for _, opt := range opts {
+				opt(&got)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("callOptions() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
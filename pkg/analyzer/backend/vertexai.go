@@ -12,6 +12,7 @@ import (
 type VertexAI struct {
 	llm       *vertex.Vertex
 	modelName string
+	options   GenerationOptions
 }
 
 func NewVertexAI(ctx context.Context, config *config.VertexAIConfig) (*VertexAI, error) {
@@ -28,12 +29,14 @@ func NewVertexAI(ctx context.Context, config *config.VertexAIConfig) (*VertexAI,
 	return &VertexAI{
 		llm:       llm,
 		modelName: config.ModelName,
+		options:   newGenerationOptions(&config.GenerationConfig),
 	}, nil
 }
 
 func (v *VertexAI) GenerateReport(
 	ctx context.Context,
 	content []llms.MessageContent,
+	defaults GenerationOptions,
 ) (string, error) {
-	return getGeneratedContent(ctx, v.llm, content)
+	return getGeneratedContent(ctx, v.llm, content, v.options.withDefaults(defaults))
 }
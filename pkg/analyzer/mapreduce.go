@@ -0,0 +1,268 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/tmc/langchaingo/llms"
+	"github.com/ymtdzzz/telemetry-glue/pkg/app/model"
+)
+
+// analyzeInChunks analyzes telemetry that does not fit in the token budget even after compaction.
+// The telemetry is split into chunks by span subtree, each chunk is summarized by the LLM (map),
+// and the final report is synthesized from the chunk summaries (reduce). Only the final report is streamed.
+func (a *Analyzer) analyzeInChunks(ctx context.Context, analysisType AnalysisType, telemetry *model.Telemetry, stream io.Writer) (string, error) {
+	budget := a.maxInputTokens - promptOverheadTokens
+	chunks := telemetry.Split(budget)
+
+	tokens, err := telemetry.RoughTokenEstimate()
+	if err != nil {
+		return "", err
+	}
+	if err := a.logger.Log(fmt.Sprintf("Telemetry is too large for a single prompt (~%d tokens); analyzing it in %d chunks...", tokens, len(chunks))); err != nil {
+		return "", err
+	}
+
+	summaries := make([]string, 0, len(chunks))
+	for i, chunk := range chunks {
+		content, err := generateChunkPrompt(analysisType, chunk, i+1, len(chunks), budget)
+		if err != nil {
+			return "", err
+		}
+		summary, err := (*a.backend).GenerateReport(ctx, content, summaryGenerationOptions)
+		if err != nil {
+			return "", fmt.Errorf("failed to summarize chunk %d/%d: %w", i+1, len(chunks), err)
+		}
+		summaries = append(summaries, summary)
+		if err := a.logger.Log(fmt.Sprintf("Summarized chunk %d/%d", i+1, len(chunks))); err != nil {
+			return "", err
+		}
+	}
+
+	summaries, err = a.combineSummaries(ctx, analysisType, summaries, budget)
+	if err != nil {
+		return "", err
+	}
+
+	if err := a.logger.Log("Synthesizing the final report from the chunk summaries..."); err != nil {
+		return "", err
+	}
+	content, err := generateSynthesisPrompt(analysisType, telemetry, summaries, a.language)
+	if err != nil {
+		return "", err
+	}
+	return (*a.backend).GenerateReport(ctx, content, reportOptions(analysisType, stream))
+}
+
+// combineSummaries merges groups of summaries with the LLM until all of them fit in the token budget,
+// so that the synthesis prompt stays within the context window however many chunks there are
+func (a *Analyzer) combineSummaries(ctx context.Context, analysisType AnalysisType, summaries []string, budget int) ([]string, error) {
+	for len(summaries) > 1 && model.EstimateTokens(strings.Join(summaries, "\n")) > budget {
+		var groups [][]string
+		var group []string
+		tokens := 0
+		for _, s := range summaries {
+			t := model.EstimateTokens(s)
+			if len(group) > 0 && tokens+t > budget {
+				groups = append(groups, group)
+				group, tokens = nil, 0
+			}
+			group = append(group, s)
+			tokens += t
+		}
+		groups = append(groups, group)
+		if len(groups) == len(summaries) {
+			// No two summaries fit together, so merging cannot reduce them any further
+			share := budget / len(summaries)
+			if err := a.logger.Log(fmt.Sprintf("Warning: the %d chunk summaries do not fit in the token budget; truncating each of them to ~%d tokens", len(summaries), share)); err != nil {
+				return nil, err
+			}
+			truncated := make([]string, 0, len(summaries))
+			for _, s := range summaries {
+				truncated = append(truncated, truncateToTokens(s, share))
+			}
+			return truncated, nil
+		}
+
+		if err := a.logger.Log(fmt.Sprintf("Combining %d chunk summaries into %d...", len(summaries), len(groups))); err != nil {
+			return nil, err
+		}
+		combined := make([]string, 0, len(groups))
+		for _, g := range groups {
+			if len(g) == 1 {
+				combined = append(combined, g[0])
+				continue
+			}
+			summary, err := (*a.backend).GenerateReport(ctx, generateCombinePrompt(analysisType, g), summaryGenerationOptions)
+			if err != nil {
+				return nil, fmt.Errorf("failed to combine chunk summaries: %w", err)
+			}
+			combined = append(combined, summary)
+		}
+		summaries = combined
+	}
+	return summaries, nil
+}
+
+// truncatedSummaryMarker is appended to the summaries truncated to fit in the token budget
+const truncatedSummaryMarker = "\n...(truncated)"
+
+// truncateToTokens truncates the text to an estimate of the number of tokens, including the marker.
+// model.EstimateTokens counts three runes per token.
+func truncateToTokens(s string, tokens int) string {
+	if model.EstimateTokens(s) <= tokens {
+		return s
+	}
+	n := max(tokens*3-len([]rune(truncatedSummaryMarker)), 0)
+	return string([]rune(s)[:n]) + truncatedSummaryMarker
+}
+
+// chunkFocus describes what the chunk summaries should extract for the analysis type
+func chunkFocus(analysisType AnalysisType) string {
+	if analysisType == AnalysisTypeError {
+		return `- Failing spans and ERROR-level logs, with their span IDs, services, operations and error messages
+- Which operations the failures propagated to or from, as far as this part shows
+- Spans whose parent is not in this part and that are failing or have failing children`
+	}
+	return `- The slowest operations, with their span IDs, services, durations and start times
+- Operations that are repeated many times (e.g., N+1 queries) and the total time they take
+- Gaps where no child operation is running, and errors or logs that may explain the latency`
+}
+
+// generateChunkPrompt generates the prompt summarizing a single chunk of the telemetry
+func generateChunkPrompt(analysisType AnalysisType, chunk *model.Telemetry, index, total, budget int) ([]llms.MessageContent, error) {
+	compacted, err := chunk.Compact(budget)
+	if err != nil {
+		return []llms.MessageContent{}, fmt.Errorf("failed to compact chunk %d/%d: %w", index, total, err)
+	}
+
+	spansCSV, logsCSV, err := compacted.AsCSV()
+	if err != nil {
+		return []llms.MessageContent{}, fmt.Errorf("failed to convert chunk %d/%d to CSV: %w", index, total, err)
+	}
+
+	system := "You are an expert in observability and distributed systems."
+	prompt := fmt.Sprintf(`The telemetry data of a trace is too large to analyze at once, so it was split into %d parts by span subtree.
+Below is part %d of %d. Summarize it for a later %s analysis of the whole trace.
+
+## Data Summary
+- Spans: %d entries
+- Logs: %d entries
+%s
+%s
+
+## What to Extract
+%s
+
+Be concise and factual: keep span IDs, durations and timestamps as they are so that the parts can be correlated later.
+Do not give recommendations yet. Write the summary in English as plain text.
+
+## Telemetry Data
+### Spans (CSV)
+%s
+
+### Logs (CSV)
+%s`,
+		total,
+		index,
+		total,
+		analysisType,
+		len(chunk.Spans),
+		len(chunk.Logs),
+		timeRangeSummary(chunk),
+		warningsSummary(compacted),
+		chunkFocus(analysisType),
+		spansCSV,
+		logsCSV,
+	)
+
+	return buildContent(system, prompt, ""), nil
+}
+
+// generateCombinePrompt generates the prompt merging several chunk summaries into one
+func generateCombinePrompt(analysisType AnalysisType, summaries []string) []llms.MessageContent {
+	system := "You are an expert in observability and distributed systems."
+	prompt := fmt.Sprintf(`Below are summaries of consecutive parts of a large trace, written for a later %s analysis of the whole trace.
+Merge them into a single concise summary, keeping the span IDs, durations and timestamps of the most significant findings.
+Write the summary in English as plain text.
+
+%s`,
+		analysisType,
+		formatSummaries(summaries),
+	)
+
+	return buildContent(system, prompt, "")
+}
+
+// generateSynthesisPrompt generates the prompt for the final report from the chunk summaries.
+// The summaries of the data and the trace structure are computed from the full telemetry.
+func generateSynthesisPrompt(analysisType AnalysisType, telemetry *model.Telemetry, summaries []string, language string) ([]llms.MessageContent, error) {
+	var system, dataSummary, requirements string
+	switch analysisType {
+	case AnalysisTypeDuration:
+		system = "You are an expert in observability and performance analysis."
+		dataSummary = fmt.Sprintf(`- Spans: %d entries
+- Logs: %d entries
+%s
+%s
+%s`,
+			len(telemetry.Spans),
+			len(telemetry.Logs),
+			timeRangeSummary(telemetry),
+			warningsSummary(telemetry),
+			traceStructureSummary(telemetry),
+		)
+		requirements = durationRequirements
+	case AnalysisTypeError:
+		system = "You are an expert in observability and failure analysis of distributed systems."
+		dataSummary = fmt.Sprintf(`- Spans: %d entries (%d failing)
+- Logs: %d entries (%d at ERROR level or above)
+%s
+%s
+
+%s`,
+			len(telemetry.Spans),
+			len(telemetry.Spans.Errors()),
+			len(telemetry.Logs),
+			len(telemetry.Logs.Errors()),
+			timeRangeSummary(telemetry),
+			warningsSummary(telemetry),
+			failingSpansDefinition,
+		)
+		requirements = errorRequirements
+	default:
+		return []llms.MessageContent{}, fmt.Errorf("unsupported analysis type: %s", analysisType)
+	}
+
+	prompt := fmt.Sprintf(`Please analyze the following trace for %s issues.
+The telemetry data was too large for a single prompt, so it was split into parts by span subtree and each part was summarized separately.
+Base the analysis on the data summary and the part summaries below, and correlate the findings across parts using span IDs and timestamps.
+
+## Data Summary
+%s
+
+## Part Summaries
+%s
+
+%s
+
+%s`,
+		analysisType,
+		dataSummary,
+		formatSummaries(summaries),
+		requirements,
+		outputFormat,
+	)
+
+	return buildContent(system, prompt, language), nil
+}
+
+func formatSummaries(summaries []string) string {
+	var s strings.Builder
+	for i, summary := range summaries {
+		fmt.Fprintf(&s, "### Part %d\n%s\n\n", i+1, strings.TrimSpace(summary))
+	}
+	return strings.TrimSpace(s.String())
+}
@@ -0,0 +1,94 @@
+package analyzer
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/tmc/langchaingo/llms"
+	"github.com/ymtdzzz/telemetry-glue/pkg/analyzer/backend"
+	"github.com/ymtdzzz/telemetry-glue/pkg/app/model"
+)
+
+// stubBackend returns the same report for every request and counts the requests
+type stubBackend struct {
+	report string
+	calls  int
+}
+
+func (b *stubBackend) GenerateReport(_ context.Context, _ []llms.MessageContent, _ backend.GenerationOptions) (string, error) {
+	b.calls++
+	return b.report, nil
+}
+
+// recordingLogger records the logged messages
+type recordingLogger struct {
+	messages []string
+}
+
+func (l *recordingLogger) Log(message string) error {
+	l.messages = append(l.messages, message)
+	return nil
+}
+
+func TestCombineSummaries(t *testing.T) {
+	const budget = 100
+
+	tests := []struct {
+		name        string
+		summaries   []string
+		wantCalls   int
+		wantCount   int
+		wantWarning bool
+	}{
+		{
+			name:      "summaries within the budget",
+			summaries: []string{strings.Repeat("a", 90), strings.Repeat("b", 90)},
+			wantCount: 2,
+		},
+		{
+			name:      "summaries merged in groups",
+			summaries: []string{strings.Repeat("a", 150), strings.Repeat("b", 150), strings.Repeat("c", 150)},
+			wantCalls: 1,
+			wantCount: 2,
+		},
+		{
+			name:        "summaries too large to merge",
+			summaries:   []string{strings.Repeat("a", 240), strings.Repeat("b", 240), strings.Repeat("c", 240)},
+			wantCount:   3,
+			wantWarning: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &stubBackend{report: strings.Repeat("m", 30)}
+			var llm backend.LLMBackend = stub
+			log := &recordingLogger{}
+			a := &Analyzer{backend: &llm, logger: log}
+
+			got, err := a.combineSummaries(context.Background(), AnalysisTypeDuration, tt.summaries, budget)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != tt.wantCount {
+				t.Errorf("combineSummaries() returned %d summaries, want %d", len(got), tt.wantCount)
+			}
+			if tokens := model.EstimateTokens(strings.Join(got, "")); tokens > budget {
+				t.Errorf("summaries have ~%d tokens, want at most %d", tokens, budget)
+			}
+			if stub.calls != tt.wantCalls {
+				t.Errorf("backend called %d times, want %d", stub.calls, tt.wantCalls)
+			}
+			warned := false
+			for _, m := range log.messages {
+				if strings.HasPrefix(m, "Warning:") {
+					warned = true
+				}
+			}
+			if warned != tt.wantWarning {
+				t.Errorf("logged %q, want a warning: %v", log.messages, tt.wantWarning)
+			}
+		})
+	}
+}
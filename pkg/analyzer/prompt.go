@@ -1,8 +1,8 @@
 package analyzer
 
 import (
-	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/tmc/langchaingo/llms"
@@ -10,29 +10,57 @@ import (
 	"github.com/ymtdzzz/telemetry-glue/pkg/app/model"
 )
 
-func generatePrompt(analysisType AnalysisType, telemetry *model.Telemetry, language string) ([]llms.MessageContent, error) {
+// durationRequirements lists what the duration analysis report should cover
+const durationRequirements = `## Analysis Requirements
+Please provide a comprehensive performance analysis including:
+
+1. **Performance Bottlenecks**: Identify the slowest operations and services, based on the critical path and self times above
+2. **Duration Analysis**: Analyze span durations and identify outliers
+3. **Critical Path**: Explain why the operations on the precomputed critical path above took as long as they did
+4. **Resource Utilization**: Look for signs of resource contention or inefficiency
+5. **Correlation Analysis**: Correlate performance issues with logs and error patterns
+6. **Optimization Recommendations**: Provide specific, actionable recommendations`
+
+// errorRequirements lists what the error analysis report should cover
+const errorRequirements = `## Analysis Requirements
+Please provide a comprehensive error analysis including:
+
+1. **Root Cause**: Identify the operation and service where the failure originated
+2. **Error Propagation**: Explain how the failure propagated through parent spans and services
+3. **Error Details**: Summarize error messages, exception types and HTTP status codes
+4. **Log Correlation**: Correlate ERROR-level logs with the failing spans using trace and span IDs
+5. **Impact**: Describe which requests or features were affected by the failure
+6. **Remediation Recommendations**: Provide specific, actionable recommendations to fix and prevent the failure`
+
+// failingSpansDefinition explains which spans are counted as failing
+const failingSpansDefinition = "Failing spans are spans with error attributes, an ERROR status, exception events or an HTTP 5xx response."
+
+// outputFormat describes the format of the report
+const outputFormat = `## Output Format
+Please structure your response with clear sections and bullet points.
+But note that it should be printed as plain text, not in markdown format.`
+
+// promptOverheadTokens is reserved in the token budget for the instructions and summaries around the telemetry data
+const promptOverheadTokens = config.PromptOverheadTokens
+
+// generatePrompt generates the prompt for the analysis. The telemetry data is taken from reduced,
+// the telemetry already reduced to fit in the token budget, while the summaries are computed from the full telemetry.
+func generatePrompt(analysisType AnalysisType, telemetry, reduced *model.Telemetry, language string) ([]llms.MessageContent, error) {
 	switch analysisType {
 	case AnalysisTypeDuration:
-		return generateDurationPrompt(telemetry, language)
+		return generateDurationPrompt(telemetry, reduced, language)
 	case AnalysisTypeError:
-		return generateErrorPrompt(telemetry, language)
+		return generateErrorPrompt(telemetry, reduced, language)
 	default:
 		return []llms.MessageContent{}, fmt.Errorf("unsupported analysis type: %s", analysisType)
 	}
 }
 
 // generateDurationPrompt generates a prompt for performance/duration analysis
-func generateDurationPrompt(telemetry *model.Telemetry, language string) ([]llms.MessageContent, error) {
-	earliest, latest := telemetry.TimeRange()
-	timeRange := ""
-	if !earliest.IsZero() && !latest.IsZero() {
-		timeRange = fmt.Sprintf("Time range: %s to %s (duration: %v)",
-			earliest.Format(time.RFC3339),
-			latest.Format(time.RFC3339),
-			latest.Sub(earliest))
-	}
+func generateDurationPrompt(telemetry, reduced *model.Telemetry, language string) ([]llms.MessageContent, error) {
+	timeRange := timeRangeSummary(telemetry)
 
-	spansCSV, logsCSV, err := telemetry.AsCSV()
+	spansCSV, logsCSV, err := reduced.AsCSV()
 	if err != nil {
 		return []llms.MessageContent{}, fmt.Errorf("failed to convert telemetry to CSV: %w", err)
 	}
@@ -44,20 +72,12 @@ func generateDurationPrompt(telemetry *model.Telemetry, language string) ([]llms
 - Spans: %d entries
 - Logs: %d entries  
 %s
+%s
+%s
 
-## Analysis Requirements
-Please provide a comprehensive performance analysis including:
-
-1. **Performance Bottlenecks**: Identify the slowest operations and services
-2. **Duration Analysis**: Analyze span durations and identify outliers
-3. **Critical Path**: Identify the critical path through the system
-4. **Resource Utilization**: Look for signs of resource contention or inefficiency
-5. **Correlation Analysis**: Correlate performance issues with logs and error patterns
-6. **Optimization Recommendations**: Provide specific, actionable recommendations
+%s
 
-## Output Format
-Please structure your response with clear sections and bullet points.
-But note that it should be printed as plain text, not in markdown format.
+%s
 
 ## Telemetry Data
 ### Spans (CSV)
@@ -68,10 +88,172 @@ But note that it should be printed as plain text, not in markdown format.
 		len(telemetry.Spans),
 		len(telemetry.Logs),
 		timeRange,
+		warningsSummary(reduced),
+		traceStructureSummary(telemetry),
+		durationRequirements,
+		outputFormat,
+		spansCSV,
+		logsCSV,
+	)
+
+	return buildContent(system, prompt, language), nil
+}
+
+// generateErrorPrompt generates a prompt for error analysis. The failing spans and error logs
+// are listed first, followed by the rest of the telemetry.
+func generateErrorPrompt(telemetry, reduced *model.Telemetry, language string) ([]llms.MessageContent, error) {
+	timeRange := timeRangeSummary(telemetry)
+
+	errorSpans := reduced.Spans.Errors()
+	errorLogs := reduced.Logs.Errors()
+
+	errorSpansCSV, err := errorSpans.AsCSV()
+	if err != nil {
+		return []llms.MessageContent{}, fmt.Errorf("failed to convert failing spans to CSV: %w", err)
+	}
+	errorLogsCSV, err := errorLogs.AsCSV()
+	if err != nil {
+		return []llms.MessageContent{}, fmt.Errorf("failed to convert error logs to CSV: %w", err)
+	}
+
+	spansCSV, logsCSV, err := reduced.WithoutErrors().AsCSV()
+	if err != nil {
+		return []llms.MessageContent{}, fmt.Errorf("failed to convert the other telemetry to CSV: %w", err)
+	}
+
+	system := "You are an expert in observability and failure analysis of distributed systems."
+	prompt := fmt.Sprintf(`Please analyze the following telemetry data for errors and failures.
+
+## Data Summary
+- Spans: %d entries (%d failing)
+- Logs: %d entries (%d at ERROR level or above)
+%s
+%s
+
+%s
+
+%s
+
+%s
+
+## Telemetry Data
+### Failing Spans (CSV)
+%s
+
+### Error Logs (CSV)
+%s
+
+### Other Spans (CSV)
+%s
+
+### Other Logs (CSV)
+%s`,
+		len(telemetry.Spans),
+		len(telemetry.Spans.Errors()),
+		len(telemetry.Logs),
+		len(telemetry.Logs.Errors()),
+		timeRange,
+		warningsSummary(reduced),
+		failingSpansDefinition,
+		errorRequirements,
+		outputFormat,
+		errorSpansCSV,
+		errorLogsCSV,
 		spansCSV,
 		logsCSV,
 	)
 
+	return buildContent(system, prompt, language), nil
+}
+
+// timeRangeSummary describes the time range covered by the telemetry
+func timeRangeSummary(telemetry *model.Telemetry) string {
+	earliest, latest := telemetry.TimeRange()
+	if earliest.IsZero() || latest.IsZero() {
+		return ""
+	}
+	return fmt.Sprintf("Time range: %s to %s (duration: %v)",
+		earliest.Format(time.RFC3339),
+		latest.Format(time.RFC3339),
+		latest.Sub(earliest))
+}
+
+// topSelfTimeSpans is the number of spans listed by their self time in the duration prompt
+const topSelfTimeSpans = 10
+
+// traceStructureSummary describes the critical path and the spans with the longest self time,
+// which are computed from the span tree as LLMs cannot reliably reconstruct it from CSV
+func traceStructureSummary(telemetry *model.Telemetry) string {
+	if len(telemetry.Spans) == 0 {
+		return ""
+	}
+	tree := telemetry.Spans.Tree()
+
+	var summary strings.Builder
+	summary.WriteString("\n## Trace Structure\n")
+	if tree.HasMultipleRoots() {
+		fmt.Fprintf(&summary, "- The trace has %d root spans; context propagation may be broken between them\n", len(tree.Roots))
+	}
+	if n := tree.MissingParents(); n > 0 {
+		fmt.Fprintf(&summary, "- %d spans reference a parent span missing from the trace; the trace may be incomplete\n", n)
+	}
+	if tree.DuplicateIDs > 0 {
+		fmt.Fprintf(&summary, "- %d spans share their span ID with another span and are shown without their parent\n", tree.DuplicateIDs)
+	}
+	if tree.CycleBreaks > 0 {
+		fmt.Fprintf(&summary, "- %d spans are in a cycle of parent span IDs and are shown without their parent\n", tree.CycleBreaks)
+	}
+
+	path := tree.CriticalPath()
+	if len(path) > 0 {
+		total := path[len(path)-1].End.Sub(path[0].Start)
+		fmt.Fprintf(&summary, "\n### Critical Path (total %s)\n", formatDuration(total))
+		summary.WriteString("Time spent in each span itself while it was blocking the end of the trace, in chronological order:\n")
+		for i, seg := range path {
+			fmt.Fprintf(&summary, "%d. %s (span %s): %s\n", i+1, spanLabel(seg.Span), seg.Span.SpanID, formatDuration(seg.Duration()))
+		}
+	}
+
+	summary.WriteString("\n### Top Self Time Spans\n")
+	summary.WriteString("Time spent in the span itself, excluding its children:\n")
+	for _, n := range tree.TopSelfTime(topSelfTimeSpans) {
+		fmt.Fprintf(&summary, "- %s (span %s): self %s / total %s\n",
+			spanLabel(n.Span), n.Span.SpanID, formatDuration(n.SelfTime), formatDuration(n.Span.Duration))
+	}
+
+	return summary.String()
+}
+
+func spanLabel(span *model.Span) string {
+	if span.ServiceName == "" {
+		return span.Name
+	}
+	return span.ServiceName + " " + span.Name
+}
+
+// formatDuration formats the duration in milliseconds as used in the CSV
+func formatDuration(d time.Duration) string {
+	return fmt.Sprintf("%.3fms", float64(d)/float64(time.Millisecond))
+}
+
+// warningsSummary describes the problems that occurred while collecting the telemetry
+func warningsSummary(telemetry *model.Telemetry) string {
+	if len(telemetry.Warnings) == 0 {
+		return ""
+	}
+
+	var summary strings.Builder
+	summary.WriteString("\n## Data Warnings\n")
+	summary.WriteString("The telemetry below is incomplete. Mention this in the report and avoid conclusions that depend on the missing data.\n")
+	for _, w := range telemetry.Warnings {
+		summary.WriteString("- " + w.String() + "\n")
+	}
+	return summary.String()
+}
+
+// buildContent builds the message content from the system and user prompts
+// along with language-specific instructions
+func buildContent(system, prompt, language string) []llms.MessageContent {
 	content := []llms.MessageContent{
 		llms.TextParts(llms.ChatMessageTypeSystem, system),
 		llms.TextParts(llms.ChatMessageTypeHuman, prompt),
@@ -86,10 +268,5 @@ Please provide the analysis report in Japanese. Keep technical terms, metrics, a
 		content = append(content, llms.TextParts(llms.ChatMessageTypeHuman, extraPrompt))
 	}
 
-	return content, nil
-}
-
-// generateErrorPrompt generates a prompt for error analysis
-func generateErrorPrompt(_ *model.Telemetry, _ string) ([]llms.MessageContent, error) {
-	return []llms.MessageContent{}, errors.New("error analysis prompt generation not implemented yet")
+	return content
 }
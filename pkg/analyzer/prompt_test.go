@@ -0,0 +1,101 @@
+package analyzer
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tmc/langchaingo/llms"
+	"github.com/ymtdzzz/telemetry-glue/pkg/app/model"
+)
+
+// promptText returns the human messages of the prompt
+func promptText(content []llms.MessageContent) string {
+	var s strings.Builder
+	for _, m := range content {
+		if m.Role != llms.ChatMessageTypeHuman {
+			continue
+		}
+		for _, p := range m.Parts {
+			if text, ok := p.(llms.TextContent); ok {
+				s.WriteString(text.Text)
+			}
+		}
+	}
+	return s.String()
+}
+
+func TestGenerateErrorPrompt(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	telemetry := &model.Telemetry{
+		Spans: model.Spans{
+			{SpanID: "root-span", Name: "GET /orders", StartTime: start, Duration: time.Second},
+			{SpanID: "failing-span", ParentSpanID: "root-span", Name: "SELECT orders", StartTime: start, Duration: 500 * time.Millisecond, Status: model.SpanStatusError},
+		},
+		Logs: model.Logs{
+			{Timestamp: start, Message: "request received", Attributes: map[string]any{"level": "INFO"}},
+			{Timestamp: start, Message: "query failed", Attributes: map[string]any{"level": "ERROR"}},
+		},
+	}
+
+	content, err := generateErrorPrompt(telemetry, telemetry, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	prompt := promptText(content)
+
+	for _, s := range []string{"GET /orders", "request received", "SELECT orders", "query failed"} {
+		if n := strings.Count(prompt, s); n != 1 {
+			t.Errorf("%q appears %d times in the prompt, want 1", s, n)
+		}
+	}
+}
+
+func TestGeneratePromptFromReducedTelemetry(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	telemetry := &model.Telemetry{
+		Spans: model.Spans{
+			{SpanID: "root-span", Name: "GET /orders", StartTime: start, Duration: time.Second},
+		},
+	}
+	for i := range 10 {
+		telemetry.Spans = append(telemetry.Spans, model.Span{
+			SpanID:       fmt.Sprintf("query-%d", i),
+			ParentSpanID: "root-span",
+			Name:         "SELECT orders",
+			StartTime:    start.Add(time.Duration(i) * 10 * time.Millisecond),
+			Duration:     5 * time.Millisecond,
+			Attributes:   map[string]any{"process.pid": i},
+		})
+	}
+
+	reduced, fits, err := telemetry.Reduce(150)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fits {
+		t.Fatal("the repeated queries should be collapsed to fit in the budget")
+	}
+
+	for _, analysisType := range []AnalysisType{AnalysisTypeDuration, AnalysisTypeError} {
+		t.Run(string(analysisType), func(t *testing.T) {
+			content, err := generatePrompt(analysisType, telemetry, reduced, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			prompt := promptText(content)
+
+			_, data, _ := strings.Cut(prompt, "## Telemetry Data")
+			if n := strings.Count(data, "query-"); n != 1 {
+				t.Errorf("%d queries are listed in the telemetry data, want 1 collapsed row", n)
+			}
+			if !strings.Contains(prompt, "collapsed 10 repeated sibling spans") {
+				t.Error("the data warnings should describe the reduction")
+			}
+			if !strings.Contains(prompt, "Spans: 11 entries") {
+				t.Error("the data summary should count the spans of the full telemetry")
+			}
+		})
+	}
+}
@@ -3,6 +3,8 @@ package app
 import (
 	"context"
 	"fmt"
+	"io"
+	"time"
 
 	"github.com/ymtdzzz/telemetry-glue/pkg/analyzer"
 	"github.com/ymtdzzz/telemetry-glue/pkg/app/config"
@@ -18,40 +20,87 @@ type App struct {
 	logger    logger.Loggable
 	analyzer  *analyzer.Analyzer
 	glue      *glue.Glue
-	traceID   string
+	traceID   model.TraceID
 	timeRange *backend.TimeRange
 }
 
-// NewApp creates a new App instance with the provided configuration
+// NewApp creates a new App instance with the provided configuration.
+// traceRef is a trace ID in any format supported by model.ParseTraceID or a URL supported by ParseTraceRef.
+// The time window in the URL is used unless a time range is given explicitly. The account and project
+// in the URL are used only when they are the configured ones or allowed by the configuration, so that
+// a pasted URL cannot point the configured credentials at other accounts or projects.
+// When no time range is known, it is discovered from the trace ID before fetching telemetry.
 func NewApp(
 	cfgPath string,
 	logger logger.Loggable,
-	traceID string,
+	traceRef string,
 	timeRange *backend.TimeRange,
 ) (*App, error) {
+	ref, err := ParseTraceRef(traceRef)
+	if err != nil {
+		return nil, err
+	}
+	id, err := model.ParseTraceID(ref.TraceID)
+	if err != nil {
+		return nil, err
+	}
+	if timeRange == nil {
+		timeRange = ref.TimeRange
+	}
+
 	cfg, err := config.LoadConfig(cfgPath)
 	if err != nil {
 		return nil, err
 	}
+	if ref.AccountID != 0 && cfg.Glue.NewRelic.HasAnyConfig() {
+		if !cfg.Glue.NewRelic.AllowsAccount(ref.AccountID) {
+			return nil, fmt.Errorf("the New Relic account %d in the trace URL is not allowed by the configuration", ref.AccountID)
+		}
+		cfg.Glue.NewRelic.AccountID = ref.AccountID
+	}
+	if ref.ProjectID != "" && cfg.Glue.GCP.HasAnyConfig() {
+		if !cfg.Glue.GCP.AllowsProject(ref.ProjectID) {
+			return nil, fmt.Errorf("the GCP project %s in the trace URL is not allowed by the configuration", ref.ProjectID)
+		}
+		cfg.Glue.GCP.ProjectID = ref.ProjectID
+	}
 
-	analyzer, err := analyzer.NewAnalyzer(&cfg.Analyzer)
+	analyzer, err := analyzer.NewAnalyzer(&cfg.Analyzer, logger)
 	if err != nil {
 		return nil, err
 	}
 
-	glue := glue.NewGlue(&cfg.Glue)
+	glue, err := glue.NewGlue(context.Background(), &cfg.Glue)
+	if err != nil {
+		return nil, err
+	}
 
 	return &App{
 		config:    cfg,
 		analyzer:  analyzer,
 		glue:      glue,
 		logger:    logger,
-		traceID:   traceID,
+		traceID:   id,
 		timeRange: timeRange,
 	}, nil
 }
 
+// RunDuration fetches telemetry and runs the duration analysis
 func (a *App) RunDuration(ctx context.Context, queryOnly bool) error {
+	return a.run(ctx, queryOnly, "Duration", a.analyzer.AnalyzeDuration)
+}
+
+// RunError fetches telemetry and runs the error analysis
+func (a *App) RunError(ctx context.Context, queryOnly bool) error {
+	return a.run(ctx, queryOnly, "Error", a.analyzer.AnalyzeError)
+}
+
+func (a *App) run(
+	ctx context.Context,
+	queryOnly bool,
+	title string,
+	analyze func(context.Context, *model.Telemetry, io.Writer) (string, error),
+) error {
 	telemetry, err := a.executeGlue(ctx)
 	if err != nil {
 		return err
@@ -65,26 +114,87 @@ func (a *App) RunDuration(ctx context.Context, queryOnly bool) error {
 		return a.logger.Log("No telemetry data found; skipping analysis.")
 	}
 
-	report, err := a.analyzer.AnalyzeDuration(ctx, telemetry)
+	header := fmt.Sprintf("Generated %s Analysis Report:", title)
+
+	// The report is streamed to the loggers supporting it as it is generated
+	var stream *reportStream
+	var w io.Writer
+	if l, ok := a.logger.(logger.StreamLoggable); ok {
+		stream = &reportStream{logger: l, header: header}
+		w = stream
+	}
+
+	report, err := analyze(ctx, telemetry, w)
+	if stream != nil {
+		if cerr := stream.close(err); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
 	if err != nil {
 		return a.logger.Log("Error during analysis: " + err.Error())
 	}
 
-	if err := a.logger.Log("Generated Duration Analysis Report:"); err != nil {
-		return err
-	}
-	if err := a.logger.Log(report); err != nil {
-		return err
+	if stream == nil || !stream.started() {
+		if err := a.logger.Log(header); err != nil {
+			return err
+		}
+		if err := a.logger.Log(report); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
+// reportStream streams the report to the logger. The stream is started on the first write
+// so that the progress logged before the report is generated stays above the report.
+type reportStream struct {
+	logger logger.StreamLoggable
+	header string
+	w      logger.Stream
+}
+
+func (s *reportStream) Write(p []byte) (int, error) {
+	if s.w == nil {
+		if err := s.logger.Log(s.header); err != nil {
+			return 0, err
+		}
+		w, err := s.logger.StartStream()
+		if err != nil {
+			return 0, err
+		}
+		s.w = w
+	}
+	return s.w.Write(p)
+}
+
+// started reports whether any part of the report was streamed
+func (s *reportStream) started() bool {
+	return s.w != nil
+}
+
+// close ends the streamed report, marking it as incomplete when the generation failed with err
+func (s *reportStream) close(err error) error {
+	if s.w == nil {
+		return nil
+	}
+	if err != nil {
+		return s.w.CloseWithError(err)
+	}
+	return s.w.Close()
+}
+
 func (a *App) executeGlue(ctx context.Context) (*model.Telemetry, error) {
 	if err := a.logger.Log("Executing glue to fetch telemetry data..."); err != nil {
 		return nil, err
 	}
 
+	if a.timeRange == nil {
+		if err := a.locateTrace(ctx); err != nil {
+			return nil, err
+		}
+	}
+
 	spanReq := &backend.SearchSpansRequest{
 		TraceID:   a.traceID,
 		TimeRange: a.timeRange,
@@ -101,6 +211,16 @@ func (a *App) executeGlue(ctx context.Context) (*model.Telemetry, error) {
 		}
 		return nil, err
 	}
+	// The warnings are logged once here so that they are shown even when the analysis is skipped or fails
+	if len(telemetry.Warnings) > 0 {
+		warnings := "Data Warnings (the telemetry may be incomplete):"
+		for _, w := range telemetry.Warnings {
+			warnings += "\n- " + w.String()
+		}
+		if err := a.logger.Log(warnings); err != nil {
+			return nil, err
+		}
+	}
 	tokenCount, err := telemetry.RoughTokenEstimate()
 	if err != nil {
 		if lerr := a.logger.Log("Error estimating token count: " + err.Error()); lerr != nil {
@@ -114,3 +234,24 @@ func (a *App) executeGlue(ctx context.Context) (*model.Telemetry, error) {
 
 	return telemetry, nil
 }
+
+// locateTrace discovers the time range of the trace when it is not given
+func (a *App) locateTrace(ctx context.Context) error {
+	if err := a.logger.Log("No time range given; locating the trace..."); err != nil {
+		return err
+	}
+
+	timeRange, err := a.glue.LocateTrace(ctx, a.traceID)
+	if err != nil {
+		if lerr := a.logger.Log("Error locating trace: " + err.Error()); lerr != nil {
+			return lerr
+		}
+		return err
+	}
+	a.timeRange = timeRange
+
+	return a.logger.Log(fmt.Sprintf("Located the trace between %s and %s",
+		timeRange.Start.Format(time.RFC3339),
+		timeRange.End.Format(time.RFC3339),
+	))
+}
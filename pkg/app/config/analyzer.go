@@ -2,8 +2,28 @@ package config
 
 import (
 	"errors"
+	"fmt"
+	"strings"
+	"time"
 )
 
+// Default input token budgets used when max_input_tokens is not configured
+const (
+	// defaultOllamaMaxInputTokens is kept small as local models usually run with a limited context window
+	defaultOllamaMaxInputTokens = 32000
+	// defaultGeminiMaxInputTokens fits the 1M token context window of Gemini models
+	defaultGeminiMaxInputTokens = 1000000
+	// defaultOpenAIMaxInputTokens fits the 128K token context window of GPT-4o models
+	defaultOpenAIMaxInputTokens = 128000
+	// defaultClaudeMaxInputTokens fits the 200K token context window of Claude models,
+	// which are also the default for Bedrock
+	defaultClaudeMaxInputTokens = 200000
+)
+
+// PromptOverheadTokens is reserved in the input token budget for the instructions and summaries
+// around the telemetry data, so max_input_tokens must be larger than it
+const PromptOverheadTokens = 4000
+
 type Language string
 
 const (
@@ -11,15 +31,55 @@ const (
 	LanguageJapanese Language = "ja"
 )
 
+// LLMBackendType represents the LLM backend used for the analysis
+type LLMBackendType string
+
+const (
+	LLMBackendTypeOllama    LLMBackendType = "ollama"
+	LLMBackendTypeGemini    LLMBackendType = "gemini"
+	LLMBackendTypeVertexAI  LLMBackendType = "vertex_ai"
+	LLMBackendTypeOpenAI    LLMBackendType = "openai"
+	LLMBackendTypeAnthropic LLMBackendType = "anthropic"
+	LLMBackendTypeBedrock   LLMBackendType = "bedrock"
+)
+
+// llmBackendTypes lists the LLM backends in the order they were historically picked
+// when no backend is selected explicitly
+var llmBackendTypes = []LLMBackendType{
+	LLMBackendTypeOllama,
+	LLMBackendTypeGemini,
+	LLMBackendTypeVertexAI,
+	LLMBackendTypeOpenAI,
+	LLMBackendTypeAnthropic,
+	LLMBackendTypeBedrock,
+}
+
 type AnalyzerConfig struct {
-	Language string         `yaml:"language" env:"LANGUAGE"` // en, ja
-	Ollama   OllamaConfig   `yaml:"ollama,omitempty" envPrefix:"OLLAMA_"`
-	Gemini   GeminiConfig   `yaml:"gemini,omitempty" envPrefix:"GEMINI_"`
-	VertexAI VertexAIConfig `yaml:"vertex_ai,omitempty" envPrefix:"VERTEX_AI_"`
+	Language string `yaml:"language" env:"LANGUAGE"` // en, ja
+	// Backend selects the LLM backend. It can be omitted when only one backend is configured.
+	Backend LLMBackendType `yaml:"backend" env:"BACKEND"`
+	// Fallbacks are the LLM backends tried in order when the selected one fails with
+	// a quota, rate limit, server or network error
+	Fallbacks []LLMBackendType `yaml:"fallbacks" env:"FALLBACKS" envSeparator:","`
+	Ollama    OllamaConfig     `yaml:"ollama,omitempty" envPrefix:"OLLAMA_"`
+	Gemini    GeminiConfig     `yaml:"gemini,omitempty" envPrefix:"GEMINI_"`
+	VertexAI  VertexAIConfig   `yaml:"vertex_ai,omitempty" envPrefix:"VERTEX_AI_"`
+	OpenAI    OpenAIConfig     `yaml:"openai,omitempty" envPrefix:"OPENAI_"`
+	Anthropic AnthropicConfig  `yaml:"anthropic,omitempty" envPrefix:"ANTHROPIC_"`
+	Bedrock   BedrockConfig    `yaml:"bedrock,omitempty" envPrefix:"BEDROCK_"`
+}
+
+// llmBackendConfig is implemented by the configuration of each LLM backend
+type llmBackendConfig interface {
+	HasAnyConfig() bool
+	validate() error
 }
 
 func (c *AnalyzerConfig) hasAnyConfig() bool {
-	return c.Language != "" || c.Ollama.HasAnyConfig() || c.Gemini.HasAnyConfig() || c.VertexAI.HasAnyConfig()
+	if c.Language != "" || c.Backend != "" || len(c.Fallbacks) > 0 {
+		return true
+	}
+	return len(c.configuredBackends()) > 0
 }
 
 func (c *AnalyzerConfig) validate() error {
@@ -30,39 +90,200 @@ func (c *AnalyzerConfig) validate() error {
 		return errors.New("unsupported language")
 	}
 
-	if c.Ollama.HasAnyConfig() {
-		return c.Ollama.validate()
+	if c.Backend == "" {
+		if len(c.Fallbacks) > 0 {
+			return errors.New("analyzer backend must be selected when fallbacks are set")
+		}
+		configured := c.configuredBackends()
+		if len(configured) == 0 {
+			return errors.New("no valid analyzer backend configuration found")
+		}
+		if len(configured) > 1 {
+			return fmt.Errorf("multiple analyzer backends are configured (%s); select one with the analyzer backend setting", joinBackendTypes(configured))
+		}
 	}
 
-	if c.Gemini.HasAnyConfig() {
-		return c.Gemini.validate()
+	seen := map[LLMBackendType]bool{}
+	for _, t := range c.BackendChain() {
+		backendCfg, ok := c.backendConfig(t)
+		if !ok {
+			return fmt.Errorf("unsupported analyzer backend: %s", t)
+		}
+		if seen[t] {
+			return fmt.Errorf("analyzer backend %s is listed more than once", t)
+		}
+		seen[t] = true
+
+		if !backendCfg.HasAnyConfig() {
+			return fmt.Errorf("the %s configuration is required for the selected analyzer backend", t)
+		}
+		if err := backendCfg.validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// BackendChain returns the selected LLM backend followed by its fallbacks in order.
+// When no backend is selected, the configured one is used.
+func (c *AnalyzerConfig) BackendChain() []LLMBackendType {
+	backend := c.Backend
+	if backend == "" {
+		configured := c.configuredBackends()
+		if len(configured) == 0 {
+			return nil
+		}
+		backend = configured[0]
 	}
+	return append([]LLMBackendType{backend}, c.Fallbacks...)
+}
+
+// MaxInputTokens returns the input token budget of the backend chain, i.e., the smallest budget
+// among the selected backend and its fallbacks so that the prompt fits in any of them.
+// The telemetry is compacted to fit in the budget before prompting, so a fallback with a small
+// context window also reduces the prompts of the selected backend.
+func (c *AnalyzerConfig) MaxInputTokens() int {
+	budget := 0
+	for _, t := range c.BackendChain() {
+		if b := c.maxInputTokens(t); budget == 0 || (b > 0 && b < budget) {
+			budget = b
+		}
+	}
+	return budget
+}
 
-	if c.VertexAI.HasAnyConfig() {
-		return c.VertexAI.validate()
+func (c *AnalyzerConfig) maxInputTokens(t LLMBackendType) int {
+	switch t {
+	case LLMBackendTypeOllama:
+		return valueOrDefaultInt(c.Ollama.MaxInputTokens, defaultOllamaMaxInputTokens)
+	case LLMBackendTypeGemini:
+		return valueOrDefaultInt(c.Gemini.MaxInputTokens, defaultGeminiMaxInputTokens)
+	case LLMBackendTypeVertexAI:
+		return valueOrDefaultInt(c.VertexAI.MaxInputTokens, defaultGeminiMaxInputTokens)
+	case LLMBackendTypeOpenAI:
+		return valueOrDefaultInt(c.OpenAI.MaxInputTokens, defaultOpenAIMaxInputTokens)
+	case LLMBackendTypeAnthropic:
+		return valueOrDefaultInt(c.Anthropic.MaxInputTokens, defaultClaudeMaxInputTokens)
+	case LLMBackendTypeBedrock:
+		return valueOrDefaultInt(c.Bedrock.MaxInputTokens, defaultClaudeMaxInputTokens)
 	}
+	return 0
+}
 
-	return errors.New("no valid analyzer backend configuration found")
+func (c *AnalyzerConfig) backendConfig(t LLMBackendType) (llmBackendConfig, bool) {
+	switch t {
+	case LLMBackendTypeOllama:
+		return &c.Ollama, true
+	case LLMBackendTypeGemini:
+		return &c.Gemini, true
+	case LLMBackendTypeVertexAI:
+		return &c.VertexAI, true
+	case LLMBackendTypeOpenAI:
+		return &c.OpenAI, true
+	case LLMBackendTypeAnthropic:
+		return &c.Anthropic, true
+	case LLMBackendTypeBedrock:
+		return &c.Bedrock, true
+	}
+	return nil, false
+}
+
+// configuredBackends returns the LLM backends with any configuration set
+func (c *AnalyzerConfig) configuredBackends() []LLMBackendType {
+	var configured []LLMBackendType
+	for _, t := range llmBackendTypes {
+		if backendCfg, _ := c.backendConfig(t); backendCfg.HasAnyConfig() {
+			configured = append(configured, t)
+		}
+	}
+	return configured
+}
+
+func joinBackendTypes(types []LLMBackendType) string {
+	names := make([]string, len(types))
+	for i, t := range types {
+		names[i] = string(t)
+	}
+	return strings.Join(names, ", ")
+}
+
+func valueOrDefaultInt(value, defaultValue int) int {
+	if value == 0 {
+		return defaultValue
+	}
+	return value
+}
+
+// GenerationConfig holds the generation options shared by the LLM backends. Unset options
+// fall back to the defaults of the analysis, and then to the defaults of the model.
+type GenerationConfig struct {
+	// Temperature controls the randomness of the output (0 to 2); lower values give more deterministic reports
+	Temperature *float64 `yaml:"temperature" env:"TEMPERATURE"`
+	// TopP is the cumulative probability of the tokens considered for sampling (0 to 1)
+	TopP *float64 `yaml:"top_p" env:"TOP_P"`
+	// Seed makes the sampling reproducible on the backends supporting it (Ollama and OpenAI)
+	Seed *int `yaml:"seed" env:"SEED"`
+	// MaxTokens is the maximum number of tokens to generate
+	MaxTokens int `yaml:"max_tokens" env:"MAX_TOKENS"`
+	// Timeout is the timeout of each request to the backend
+	Timeout time.Duration `yaml:"timeout" env:"TIMEOUT"`
+}
+
+func (c *GenerationConfig) validate(backend string) error {
+	if c.Temperature != nil && (*c.Temperature < 0 || *c.Temperature > 2) {
+		return fmt.Errorf("%s Temperature must be between 0 and 2", backend)
+	}
+	if c.TopP != nil && (*c.TopP <= 0 || *c.TopP > 1) {
+		return fmt.Errorf("%s Top P must be greater than 0 and at most 1", backend)
+	}
+	if c.MaxTokens < 0 {
+		return fmt.Errorf("%s Max Tokens must not be negative", backend)
+	}
+	if c.Timeout < 0 {
+		return fmt.Errorf("%s Timeout must not be negative", backend)
+	}
+	return nil
+}
+
+// validateMaxInputTokens validates max_input_tokens, which is 0 when it is not configured
+func validateMaxInputTokens(backend string, tokens int) error {
+	if tokens < 0 {
+		return fmt.Errorf("%s Max Input Tokens must not be negative", backend)
+	}
+	if tokens > 0 && tokens <= PromptOverheadTokens {
+		return fmt.Errorf("%s Max Input Tokens must be greater than the prompt overhead of %d tokens", backend, PromptOverheadTokens)
+	}
+	return nil
 }
 
 type OllamaConfig struct {
 	ModelName string `yaml:"model_name" env:"MODEL_NAME"`
+	// ServerURL is the URL of the Ollama server (default: http://localhost:11434)
+	ServerURL        string `yaml:"server_url" env:"SERVER_URL"`
+	MaxInputTokens   int    `yaml:"max_input_tokens" env:"MAX_INPUT_TOKENS"`
+	GenerationConfig `yaml:",inline"`
 }
 
 func (c *OllamaConfig) HasAnyConfig() bool {
-	return c.ModelName != ""
+	return c.ModelName != "" || c.ServerURL != ""
 }
 
 func (c *OllamaConfig) validate() error {
 	if c.ModelName == "" {
 		return errors.New("ollama Model Name is required")
 	}
-	return nil
+	if err := validateMaxInputTokens("ollama", c.MaxInputTokens); err != nil {
+		return err
+	}
+	return c.GenerationConfig.validate("ollama")
 }
 
 type GeminiConfig struct {
-	ModelName string `yaml:"model_name" env:"mODEL_NAME"`
-	APIKey    string `yaml:"api_key" env:"API_KEY"`
+	ModelName        string `yaml:"model_name" env:"MODEL_NAME"`
+	APIKey           string `yaml:"api_key" env:"API_KEY"`
+	MaxInputTokens   int    `yaml:"max_input_tokens" env:"MAX_INPUT_TOKENS"`
+	GenerationConfig `yaml:",inline"`
 }
 
 func (c *GeminiConfig) HasAnyConfig() bool {
@@ -76,13 +297,18 @@ func (c *GeminiConfig) validate() error {
 	if c.APIKey == "" {
 		return errors.New("gemini API Key is required")
 	}
-	return nil
+	if err := validateMaxInputTokens("gemini", c.MaxInputTokens); err != nil {
+		return err
+	}
+	return c.GenerationConfig.validate("gemini")
 }
 
 type VertexAIConfig struct {
-	ModelName string `yaml:"model_name" env:"MODEL_NAME"`
-	ProjectID string `yaml:"project_id" env:"PROJECT_ID"`
-	Location  string `yaml:"location" env:"LOCATION"`
+	ModelName        string `yaml:"model_name" env:"MODEL_NAME"`
+	ProjectID        string `yaml:"project_id" env:"PROJECT_ID"`
+	Location         string `yaml:"location" env:"LOCATION"`
+	MaxInputTokens   int    `yaml:"max_input_tokens" env:"MAX_INPUT_TOKENS"`
+	GenerationConfig `yaml:",inline"`
 }
 
 func (c *VertexAIConfig) HasAnyConfig() bool {
@@ -99,5 +325,121 @@ func (c *VertexAIConfig) validate() error {
 	if c.Location == "" {
 		return errors.New("vertex AI Location is required")
 	}
-	return nil
+	if err := validateMaxInputTokens("vertex AI", c.MaxInputTokens); err != nil {
+		return err
+	}
+	return c.GenerationConfig.validate("vertex AI")
+}
+
+// OpenAIAPIType represents the flavor of the OpenAI API
+type OpenAIAPIType string
+
+const (
+	OpenAIAPITypeOpenAI  OpenAIAPIType = "openai"
+	OpenAIAPITypeAzure   OpenAIAPIType = "azure"
+	OpenAIAPITypeAzureAD OpenAIAPIType = "azure_ad"
+)
+
+// OpenAIConfig configures OpenAI or any OpenAI-compatible API such as Azure OpenAI, vLLM, LM Studio or a gateway
+type OpenAIConfig struct {
+	ModelName string `yaml:"model_name" env:"MODEL_NAME"`
+	// BaseURL is the base URL of the API (e.g., http://localhost:8000/v1). Defaults to the OpenAI API.
+	BaseURL string `yaml:"base_url" env:"BASE_URL"`
+	// APIKey is optional when BaseURL is set, as local servers usually do not require one
+	APIKey       string `yaml:"api_key" env:"API_KEY"`
+	Organization string `yaml:"organization" env:"ORGANIZATION"`
+	// APIType is openai (default), azure or azure_ad
+	APIType string `yaml:"api_type" env:"API_TYPE"`
+	// APIVersion is required for Azure OpenAI (e.g., 2024-10-21)
+	APIVersion       string `yaml:"api_version" env:"API_VERSION"`
+	MaxInputTokens   int    `yaml:"max_input_tokens" env:"MAX_INPUT_TOKENS"`
+	GenerationConfig `yaml:",inline"`
+}
+
+func (c *OpenAIConfig) HasAnyConfig() bool {
+	return c.ModelName != "" || c.BaseURL != "" || c.APIKey != ""
+}
+
+func (c *OpenAIConfig) validate() error {
+	if c.ModelName == "" {
+		return errors.New("openai Model Name is required")
+	}
+	if c.APIKey == "" && c.BaseURL == "" {
+		return errors.New("openai API Key is required unless Base URL is set")
+	}
+	switch OpenAIAPIType(c.APIType) {
+	case "", OpenAIAPITypeOpenAI:
+	case OpenAIAPITypeAzure, OpenAIAPITypeAzureAD:
+		if c.BaseURL == "" {
+			return errors.New("openai Base URL is required for Azure")
+		}
+		if c.APIVersion == "" {
+			return errors.New("openai API Version is required for Azure")
+		}
+	default:
+		return fmt.Errorf("unsupported openai API type: %s", c.APIType)
+	}
+	if err := validateMaxInputTokens("openai", c.MaxInputTokens); err != nil {
+		return err
+	}
+	return c.GenerationConfig.validate("openai")
+}
+
+type AnthropicConfig struct {
+	ModelName string `yaml:"model_name" env:"MODEL_NAME"`
+	APIKey    string `yaml:"api_key" env:"API_KEY"`
+	// BaseURL overrides the Anthropic API endpoint, e.g., for a gateway
+	BaseURL        string `yaml:"base_url" env:"BASE_URL"`
+	MaxInputTokens int    `yaml:"max_input_tokens" env:"MAX_INPUT_TOKENS"`
+	// GenerationConfig.MaxTokens is required by the Anthropic API (default: 4096)
+	GenerationConfig `yaml:",inline"`
+}
+
+func (c *AnthropicConfig) HasAnyConfig() bool {
+	return c.ModelName != "" || c.APIKey != ""
+}
+
+func (c *AnthropicConfig) validate() error {
+	if c.ModelName == "" {
+		return errors.New("anthropic Model Name is required")
+	}
+	if c.APIKey == "" {
+		return errors.New("anthropic API Key is required")
+	}
+	if err := validateMaxInputTokens("anthropic", c.MaxInputTokens); err != nil {
+		return err
+	}
+	return c.GenerationConfig.validate("anthropic")
+}
+
+// BedrockConfig configures AWS Bedrock. Credentials are resolved by the default
+// AWS credential chain (environment variables, shared config files, IAM roles).
+type BedrockConfig struct {
+	// ModelName is the model ID or inference profile ID (e.g., anthropic.claude-3-5-sonnet-20240620-v1:0)
+	ModelName string `yaml:"model_name" env:"MODEL_NAME"`
+	Region    string `yaml:"region" env:"REGION"`
+	// ModelProvider is required when it cannot be inferred from ModelName, e.g., for ARNs (anthropic, amazon, meta, ...)
+	ModelProvider string `yaml:"model_provider" env:"MODEL_PROVIDER"`
+	// Profile is the shared config profile to load the credentials from
+	Profile        string `yaml:"profile" env:"PROFILE"`
+	MaxInputTokens int    `yaml:"max_input_tokens" env:"MAX_INPUT_TOKENS"`
+	// GenerationConfig.MaxTokens defaults to 4096 as most Bedrock models require it
+	GenerationConfig `yaml:",inline"`
+}
+
+func (c *BedrockConfig) HasAnyConfig() bool {
+	return c.ModelName != "" || c.Region != ""
+}
+
+func (c *BedrockConfig) validate() error {
+	if c.ModelName == "" {
+		return errors.New("bedrock Model Name is required")
+	}
+	if c.Region == "" {
+		return errors.New("bedrock Region is required")
+	}
+	if err := validateMaxInputTokens("bedrock", c.MaxInputTokens); err != nil {
+		return err
+	}
+	return c.GenerationConfig.validate("bedrock")
 }
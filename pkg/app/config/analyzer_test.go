@@ -0,0 +1,204 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+var (
+	testOllama = OllamaConfig{ModelName: "llama3"}
+	testGemini = GeminiConfig{ModelName: "gemini-2.5-pro", APIKey: "key"}
+	testOpenAI = OpenAIConfig{ModelName: "gpt-4o", APIKey: "key"}
+)
+
+func TestAnalyzerConfigValidate(t *testing.T) {
+	negative := -1.0
+
+	tests := []struct {
+		name    string
+		config  AnalyzerConfig
+		wantErr string
+	}{
+		{
+			name:   "single configured backend",
+			config: AnalyzerConfig{Language: "en", Ollama: testOllama},
+		},
+		{
+			name:   "selected backend with fallbacks",
+			config: AnalyzerConfig{Language: "ja", Backend: LLMBackendTypeGemini, Fallbacks: []LLMBackendType{LLMBackendTypeOllama}, Gemini: testGemini, Ollama: testOllama},
+		},
+		{
+			name:   "selected backend among several configured",
+			config: AnalyzerConfig{Language: "en", Backend: LLMBackendTypeOpenAI, Gemini: testGemini, OpenAI: testOpenAI},
+		},
+		{
+			name:    "missing language",
+			config:  AnalyzerConfig{Ollama: testOllama},
+			wantErr: "analyzer language is required",
+		},
+		{
+			name:    "unsupported language",
+			config:  AnalyzerConfig{Language: "fr", Ollama: testOllama},
+			wantErr: "unsupported language",
+		},
+		{
+			name:    "no backend",
+			config:  AnalyzerConfig{Language: "en"},
+			wantErr: "no valid analyzer backend configuration found",
+		},
+		{
+			name:    "several backends without selection",
+			config:  AnalyzerConfig{Language: "en", Ollama: testOllama, Gemini: testGemini},
+			wantErr: "multiple analyzer backends are configured (ollama, gemini); select one with the analyzer backend setting",
+		},
+		{
+			name:    "fallbacks without selection",
+			config:  AnalyzerConfig{Language: "en", Fallbacks: []LLMBackendType{LLMBackendTypeOllama}, Ollama: testOllama},
+			wantErr: "analyzer backend must be selected when fallbacks are set",
+		},
+		{
+			name:    "unsupported backend",
+			config:  AnalyzerConfig{Language: "en", Backend: "unknown"},
+			wantErr: "unsupported analyzer backend: unknown",
+		},
+		{
+			name:    "unsupported fallback",
+			config:  AnalyzerConfig{Language: "en", Backend: LLMBackendTypeOllama, Fallbacks: []LLMBackendType{"unknown"}, Ollama: testOllama},
+			wantErr: "unsupported analyzer backend: unknown",
+		},
+		{
+			name:    "backend listed twice",
+			config:  AnalyzerConfig{Language: "en", Backend: LLMBackendTypeOllama, Fallbacks: []LLMBackendType{LLMBackendTypeOllama}, Ollama: testOllama},
+			wantErr: "analyzer backend ollama is listed more than once",
+		},
+		{
+			name:    "selected backend not configured",
+			config:  AnalyzerConfig{Language: "en", Backend: LLMBackendTypeGemini, Ollama: testOllama},
+			wantErr: "the gemini configuration is required for the selected analyzer backend",
+		},
+		{
+			name:    "fallback not configured",
+			config:  AnalyzerConfig{Language: "en", Backend: LLMBackendTypeOllama, Fallbacks: []LLMBackendType{LLMBackendTypeBedrock}, Ollama: testOllama},
+			wantErr: "the bedrock configuration is required for the selected analyzer backend",
+		},
+		{
+			name:    "invalid fallback configuration",
+			config:  AnalyzerConfig{Language: "en", Backend: LLMBackendTypeOllama, Fallbacks: []LLMBackendType{LLMBackendTypeGemini}, Ollama: testOllama, Gemini: GeminiConfig{ModelName: "gemini-2.5-pro"}},
+			wantErr: "gemini API Key is required",
+		},
+		{
+			name:   "max input tokens above the prompt overhead",
+			config: AnalyzerConfig{Language: "en", Ollama: OllamaConfig{ModelName: "llama3", MaxInputTokens: PromptOverheadTokens + 1}},
+		},
+		{
+			name:    "max input tokens within the prompt overhead",
+			config:  AnalyzerConfig{Language: "en", Ollama: OllamaConfig{ModelName: "llama3", MaxInputTokens: PromptOverheadTokens}},
+			wantErr: "ollama Max Input Tokens must be greater than the prompt overhead of 4000 tokens",
+		},
+		{
+			name:    "negative max input tokens",
+			config:  AnalyzerConfig{Language: "en", Gemini: GeminiConfig{ModelName: "gemini-2.5-pro", APIKey: "key", MaxInputTokens: -1}},
+			wantErr: "gemini Max Input Tokens must not be negative",
+		},
+		{
+			name:    "fallback max input tokens within the prompt overhead",
+			config:  AnalyzerConfig{Language: "en", Backend: LLMBackendTypeGemini, Fallbacks: []LLMBackendType{LLMBackendTypeOpenAI}, Gemini: testGemini, OpenAI: OpenAIConfig{ModelName: "gpt-4o", APIKey: "key", MaxInputTokens: 1000}},
+			wantErr: "openai Max Input Tokens must be greater than the prompt overhead of 4000 tokens",
+		},
+		{
+			name:    "invalid generation options",
+			config:  AnalyzerConfig{Language: "en", Ollama: OllamaConfig{ModelName: "llama3", GenerationConfig: GenerationConfig{Temperature: &negative}}},
+			wantErr: "ollama Temperature must be between 0 and 2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validate() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAnalyzerConfigBackendChain(t *testing.T) {
+	tests := []struct {
+		name   string
+		config AnalyzerConfig
+		want   []LLMBackendType
+	}{
+		{
+			name: "no backend",
+		},
+		{
+			name:   "configured backend",
+			config: AnalyzerConfig{OpenAI: testOpenAI},
+			want:   []LLMBackendType{LLMBackendTypeOpenAI},
+		},
+		{
+			name:   "selected backend with fallbacks",
+			config: AnalyzerConfig{Backend: LLMBackendTypeGemini, Fallbacks: []LLMBackendType{LLMBackendTypeOpenAI, LLMBackendTypeOllama}},
+			want:   []LLMBackendType{LLMBackendTypeGemini, LLMBackendTypeOpenAI, LLMBackendTypeOllama},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.config.BackendChain(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("BackendChain() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnalyzerConfigMaxInputTokens(t *testing.T) {
+	tests := []struct {
+		name   string
+		config AnalyzerConfig
+		want   int
+	}{
+		{
+			name:   "default of the backend",
+			config: AnalyzerConfig{Gemini: testGemini},
+			want:   defaultGeminiMaxInputTokens,
+		},
+		{
+			name:   "configured budget",
+			config: AnalyzerConfig{Gemini: GeminiConfig{ModelName: "gemini-2.5-pro", APIKey: "key", MaxInputTokens: 500000}},
+			want:   500000,
+		},
+		{
+			name:   "smallest budget of the chain",
+			config: AnalyzerConfig{Backend: LLMBackendTypeGemini, Fallbacks: []LLMBackendType{LLMBackendTypeOllama}, Gemini: testGemini, Ollama: testOllama},
+			want:   defaultOllamaMaxInputTokens,
+		},
+		{
+			name: "configured budget of the fallback",
+			config: AnalyzerConfig{
+				Backend:   LLMBackendTypeGemini,
+				Fallbacks: []LLMBackendType{LLMBackendTypeOllama},
+				Gemini:    testGemini,
+				Ollama:    OllamaConfig{ModelName: "llama3", MaxInputTokens: 128000},
+			},
+			want: 128000,
+		},
+		{
+			name: "no backend",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.config.MaxInputTokens(); got != tt.want {
+				t.Errorf("MaxInputTokens() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
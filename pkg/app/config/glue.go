@@ -1,24 +1,65 @@
 package config
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"text/template"
+	"time"
+)
 
 type BackendType string
 
 const (
 	BackendTypeNewRelic BackendType = "newrelic"
+	BackendTypeGCP      BackendType = "gcp"
+	BackendTypeJaeger   BackendType = "jaeger"
+	BackendTypeTempo    BackendType = "tempo"
+	BackendTypeLoki     BackendType = "loki"
+	// BackendTypeElasticsearch also covers OpenSearch as both share the _search API
+	BackendTypeElasticsearch BackendType = "elasticsearch"
+	BackendTypeFile          BackendType = "file"
 )
 
 type GlueConfig struct {
-	NewRelic    NewRelicConfig `yaml:"newrelic,omitempty" envPrefix:"NEW_RELIC_"`
-	SpanBackend BackendType    `yaml:"span" env:"SPAN_BACKEND"`
-	LogBackend  BackendType    `yaml:"log" env:"LOG_BACKEND"`
+	NewRelic      NewRelicConfig      `yaml:"newrelic,omitempty" envPrefix:"NEW_RELIC_"`
+	GCP           GCPConfig           `yaml:"gcp,omitempty" envPrefix:"GCP_"`
+	Jaeger        JaegerConfig        `yaml:"jaeger,omitempty" envPrefix:"JAEGER_"`
+	Tempo         TempoConfig         `yaml:"tempo,omitempty" envPrefix:"TEMPO_"`
+	Loki          LokiConfig          `yaml:"loki,omitempty" envPrefix:"LOKI_"`
+	Elasticsearch ElasticsearchConfig `yaml:"elasticsearch,omitempty" envPrefix:"ELASTICSEARCH_"`
+	File          FileConfig          `yaml:"file,omitempty" envPrefix:"FILE_"`
+	SpanBackend   BackendType         `yaml:"span" env:"SPAN_BACKEND"`
+	LogBackend    BackendType         `yaml:"log" env:"LOG_BACKEND"`
+	// PartialResults keeps the fetched spans when the log fetch fails and reports the failure as a warning
+	PartialResults bool `yaml:"partial_results" env:"PARTIAL_RESULTS"`
+	// LocateLookback is how far back a trace is searched when no start time is given (default: 168h)
+	LocateLookback time.Duration `yaml:"locate_lookback" env:"LOCATE_LOOKBACK"`
 }
 
 func (c *GlueConfig) hasAnyConfig() bool {
-	return c.SpanBackend != "" || c.LogBackend != "" || c.NewRelic.HasAnyConfig()
+	return c.SpanBackend != "" || c.LogBackend != "" || c.NewRelic.HasAnyConfig() || c.GCP.HasAnyConfig() ||
+		c.Jaeger.HasAnyConfig() || c.Tempo.HasAnyConfig() || c.Loki.HasAnyConfig() || c.Elasticsearch.HasAnyConfig() ||
+		c.File.HasAnyConfig()
+}
+
+func (t BackendType) isSupported() bool {
+	switch t {
+	case BackendTypeNewRelic, BackendTypeGCP, BackendTypeJaeger, BackendTypeTempo, BackendTypeLoki,
+		BackendTypeElasticsearch, BackendTypeFile:
+		return true
+	}
+	return false
 }
 
 func (c *GlueConfig) validate() error {
+	if c.SpanBackend != "" && !c.SpanBackend.isSupported() {
+		return fmt.Errorf("unsupported span backend: %s", c.SpanBackend)
+	}
+	if c.LogBackend != "" && !c.LogBackend.isSupported() {
+		return fmt.Errorf("unsupported log backend: %s", c.LogBackend)
+	}
+
 	if c.SpanBackend == BackendTypeNewRelic || c.LogBackend == BackendTypeNewRelic {
 		if !c.NewRelic.HasAnyConfig() {
 			return errors.New("the New Relic configuration is required for the selected backend")
@@ -28,6 +69,74 @@ func (c *GlueConfig) validate() error {
 		}
 	}
 
+	if c.SpanBackend == BackendTypeGCP {
+		return errors.New("the GCP backend supports logs only")
+	}
+	if c.LogBackend == BackendTypeGCP {
+		if !c.GCP.HasAnyConfig() {
+			return errors.New("the GCP configuration is required for the selected backend")
+		}
+		if err := c.GCP.validate(); err != nil {
+			return err
+		}
+	}
+
+	if c.LogBackend == BackendTypeJaeger {
+		return errors.New("the Jaeger backend supports spans only")
+	}
+	if c.SpanBackend == BackendTypeJaeger {
+		if !c.Jaeger.HasAnyConfig() {
+			return errors.New("the Jaeger configuration is required for the selected backend")
+		}
+		if err := c.Jaeger.validate(); err != nil {
+			return err
+		}
+	}
+
+	if c.LogBackend == BackendTypeTempo {
+		return errors.New("the Tempo backend supports spans only")
+	}
+	if c.SpanBackend == BackendTypeTempo {
+		if !c.Tempo.HasAnyConfig() {
+			return errors.New("the Tempo configuration is required for the selected backend")
+		}
+		if err := c.Tempo.validate(); err != nil {
+			return err
+		}
+	}
+
+	if c.SpanBackend == BackendTypeLoki {
+		return errors.New("the Loki backend supports logs only")
+	}
+	if c.LogBackend == BackendTypeLoki {
+		if !c.Loki.HasAnyConfig() {
+			return errors.New("the Loki configuration is required for the selected backend")
+		}
+		if err := c.Loki.validate(); err != nil {
+			return err
+		}
+	}
+
+	if c.SpanBackend == BackendTypeElasticsearch || c.LogBackend == BackendTypeElasticsearch {
+		if !c.Elasticsearch.HasAnyConfig() {
+			return errors.New("the Elasticsearch configuration is required for the selected backend")
+		}
+		if err := c.Elasticsearch.validate(); err != nil {
+			return err
+		}
+	}
+
+	if c.SpanBackend == BackendTypeFile && c.File.SpanPath == "" {
+		return errors.New("the span file path is required for the selected backend")
+	}
+	if c.LogBackend == BackendTypeFile && c.File.LogPath == "" {
+		return errors.New("the log file path is required for the selected backend")
+	}
+
+	if c.LocateLookback < 0 {
+		return errors.New("the locate lookback must not be negative")
+	}
+
 	if c.SpanBackend == "" && c.LogBackend == "" {
 		return errors.New("at least one backend must be configured")
 	}
@@ -38,12 +147,21 @@ func (c *GlueConfig) validate() error {
 type NewRelicConfig struct {
 	APIKey    string `yaml:"api_key" env:"API_KEY"`
 	AccountID int    `yaml:"account_id" env:"ACCOUNT_ID"`
+	MaxSpans  int    `yaml:"max_spans" env:"MAX_SPANS"` // default: 20000
+	MaxLogs   int    `yaml:"max_logs" env:"MAX_LOGS"`   // default: 20000
+	// AllowedAccountIDs are the other accounts that trace URLs may select. The API key must have access to them.
+	AllowedAccountIDs []int `yaml:"allowed_account_ids" env:"ALLOWED_ACCOUNT_IDS"`
 }
 
 func (c *NewRelicConfig) HasAnyConfig() bool {
 	return c.APIKey != "" || c.AccountID != 0
 }
 
+// AllowsAccount reports whether the account is the configured one or one of the allowed accounts
+func (c *NewRelicConfig) AllowsAccount(accountID int) bool {
+	return accountID == c.AccountID || slices.Contains(c.AllowedAccountIDs, accountID)
+}
+
 func (c *NewRelicConfig) validate() error {
 	if c.APIKey == "" {
 		return errors.New("the New Relic API key is required")
@@ -51,5 +169,142 @@ func (c *NewRelicConfig) validate() error {
 	if c.AccountID == 0 {
 		return errors.New("the New Relic Account ID is required")
 	}
+	if c.MaxSpans < 0 {
+		return errors.New("the New Relic max spans must not be negative")
+	}
+	if c.MaxLogs < 0 {
+		return errors.New("the New Relic max logs must not be negative")
+	}
+	return nil
+}
+
+type GCPConfig struct {
+	ProjectID string `yaml:"project_id" env:"PROJECT_ID"`
+	// AllowedProjectIDs are the other projects that trace URLs may select. The credentials must have access to them.
+	AllowedProjectIDs []string `yaml:"allowed_project_ids" env:"ALLOWED_PROJECT_IDS"`
+}
+
+func (c *GCPConfig) HasAnyConfig() bool {
+	return c.ProjectID != ""
+}
+
+// AllowsProject reports whether the project is the configured one or one of the allowed projects
+func (c *GCPConfig) AllowsProject(projectID string) bool {
+	return projectID == c.ProjectID || slices.Contains(c.AllowedProjectIDs, projectID)
+}
+
+func (c *GCPConfig) validate() error {
+	if c.ProjectID == "" {
+		return errors.New("the GCP Project ID is required")
+	}
+	return nil
+}
+
+type JaegerConfig struct {
+	URL string `yaml:"url" env:"URL"`
+}
+
+func (c *JaegerConfig) HasAnyConfig() bool {
+	return c.URL != ""
+}
+
+func (c *JaegerConfig) validate() error {
+	if c.URL == "" {
+		return errors.New("the Jaeger Query URL is required")
+	}
+	return nil
+}
+
+type TempoConfig struct {
+	URL      string `yaml:"url" env:"URL"`
+	TenantID string `yaml:"tenant_id" env:"TENANT_ID"` // sent as X-Scope-OrgID for multi-tenant setups
+}
+
+func (c *TempoConfig) HasAnyConfig() bool {
+	return c.URL != "" || c.TenantID != ""
+}
+
+func (c *TempoConfig) validate() error {
+	if c.URL == "" {
+		return errors.New("the Tempo URL is required")
+	}
+	return nil
+}
+
+type LokiConfig struct {
+	URL         string `yaml:"url" env:"URL"`
+	TenantID    string `yaml:"tenant_id" env:"TENANT_ID"` // sent as X-Scope-OrgID for multi-tenant setups
+	Username    string `yaml:"username" env:"USERNAME"`
+	Password    string `yaml:"password" env:"PASSWORD"`
+	BearerToken string `yaml:"bearer_token" env:"BEARER_TOKEN"`
+	// Query is a LogQL template where {{.TraceID}} is replaced with the trace ID as given
+	// ({{.TraceIDW3C}}, {{.TraceIDHex}}, {{.XRayTraceID}} and {{.DatadogTraceID}} are also available)
+	// e.g., `{namespace="x"} |= "{{.TraceID}}"` or `{namespace="x"} | json | trace_id="{{.TraceID}}"`
+	Query string `yaml:"query" env:"QUERY"`
+}
+
+func (c *LokiConfig) HasAnyConfig() bool {
+	return c.URL != "" || c.TenantID != "" || c.Username != "" || c.Password != "" ||
+		c.BearerToken != "" || c.Query != ""
+}
+
+func (c *LokiConfig) validate() error {
+	if c.URL == "" {
+		return errors.New("the Loki URL is required")
+	}
+	if c.Username != "" && c.BearerToken != "" {
+		return errors.New("only one of the Loki basic auth and bearer token can be configured")
+	}
+	if c.Query != "" {
+		if _, err := template.New("loki").Parse(c.Query); err != nil {
+			return fmt.Errorf("invalid Loki query template: %w", err)
+		}
+	}
+	return nil
+}
+
+type ElasticsearchConfig struct {
+	URL      string `yaml:"url" env:"URL"`
+	Username string `yaml:"username" env:"USERNAME"`
+	Password string `yaml:"password" env:"PASSWORD"`
+	APIKey   string `yaml:"api_key" env:"API_KEY"`
+	// LogIndex and SpanIndex are index patterns searched for logs and spans (default: "logs-*", "traces-apm*")
+	LogIndex  string `yaml:"log_index" env:"LOG_INDEX"`
+	SpanIndex string `yaml:"span_index" env:"SPAN_INDEX"`
+	// TraceIDField is the document field holding the trace ID (e.g., ECS "trace.id", OTel "traceId").
+	// Field settings default to the ECS field names.
+	TraceIDField   string `yaml:"trace_id_field" env:"TRACE_ID_FIELD"`
+	TraceIDFormat  string `yaml:"trace_id_format" env:"TRACE_ID_FORMAT"` // w3c (default), hex, xray, datadog
+	SpanIDField    string `yaml:"span_id_field" env:"SPAN_ID_FIELD"`
+	TimestampField string `yaml:"timestamp_field" env:"TIMESTAMP_FIELD"`
+	MessageField   string `yaml:"message_field" env:"MESSAGE_FIELD"`
+}
+
+func (c *ElasticsearchConfig) HasAnyConfig() bool {
+	return c.URL != "" || c.Username != "" || c.Password != "" || c.APIKey != ""
+}
+
+func (c *ElasticsearchConfig) validate() error {
+	if c.URL == "" {
+		return errors.New("the Elasticsearch URL is required")
+	}
+	if c.Username != "" && c.APIKey != "" {
+		return errors.New("only one of the Elasticsearch basic auth and API key can be configured")
+	}
+	switch c.TraceIDFormat {
+	case "", "w3c", "hex", "xray", "datadog":
+	default:
+		return fmt.Errorf("unsupported Elasticsearch trace ID format: %s", c.TraceIDFormat)
+	}
 	return nil
 }
+
+type FileConfig struct {
+	// SpanPath and LogPath are file paths or glob patterns of OTLP JSON files
+	SpanPath string `yaml:"span_path" env:"SPAN_PATH"`
+	LogPath  string `yaml:"log_path" env:"LOG_PATH"`
+}
+
+func (c *FileConfig) HasAnyConfig() bool {
+	return c.SpanPath != "" || c.LogPath != ""
+}
@@ -1,6 +1,23 @@
 package logger
 
+import "io"
+
 // Loggable represents an entity that can output log messages
 type Loggable interface {
 	Log(message string) error
 }
+
+// StreamLoggable represents a logger that can also output a message incrementally while it is generated
+type StreamLoggable interface {
+	Loggable
+	// StartStream starts a new message. The text written to the returned stream is appended
+	// to the message, and the stream must be closed once the message is complete.
+	StartStream() (Stream, error)
+}
+
+// Stream is a message output incrementally
+type Stream interface {
+	io.WriteCloser
+	// CloseWithError ends the message marked as incomplete because of the error
+	CloseWithError(err error) error
+}
@@ -1,6 +1,25 @@
 package logger
 
-import "github.com/slack-go/slack"
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+const (
+	// slackStreamUpdateInterval is the minimum interval between the updates of a streamed message,
+	// which keeps chat.update calls well within the Slack rate limits
+	slackStreamUpdateInterval = 2 * time.Second
+	// slackStreamPlaceholder is posted when a stream starts, as Slack does not accept empty messages
+	slackStreamPlaceholder = "..."
+	// slackStreamCursor is appended to a streamed message until it is complete
+	slackStreamCursor = " ..."
+	// slackStreamIncomplete is appended to a streamed message that failed before it was complete
+	slackStreamIncomplete = "\n\n:warning: The message is incomplete: %v"
+)
 
 // SlackLogger is a logger that sends logs to a Slack channel
 type SlackLogger struct {
@@ -26,3 +45,84 @@ func (l *SlackLogger) Log(message string) error {
 	)
 	return err
 }
+
+// StartStream posts a placeholder message to the thread and returns a writer that updates
+// the message with the text written so far, at most once per slackStreamUpdateInterval
+func (l *SlackLogger) StartStream() (Stream, error) {
+	_, ts, err := l.client.PostMessage(
+		l.channelID,
+		slack.MsgOptionText(slackStreamPlaceholder, false),
+		slack.MsgOptionTS(l.threadTS),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &slackStream{
+		logger:     l,
+		ts:         ts,
+		nextUpdate: time.Now().Add(slackStreamUpdateInterval),
+	}, nil
+}
+
+type slackStream struct {
+	logger     *SlackLogger
+	ts         string
+	text       strings.Builder
+	nextUpdate time.Time
+}
+
+func (s *slackStream) Write(p []byte) (int, error) {
+	s.text.Write(p)
+	if time.Now().Before(s.nextUpdate) {
+		return len(p), nil
+	}
+
+	err := s.update(s.text.String() + slackStreamCursor)
+	var rateLimited *slack.RateLimitedError
+	if errors.As(err, &rateLimited) {
+		// Skip this update; the text is sent with a later one
+		s.nextUpdate = time.Now().Add(rateLimited.RetryAfter)
+		return len(p), nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	s.nextUpdate = time.Now().Add(slackStreamUpdateInterval)
+	return len(p), nil
+}
+
+// Close updates the message with the complete text
+func (s *slackStream) Close() error {
+	text := s.text.String()
+	if strings.TrimSpace(text) == "" {
+		text = slackStreamPlaceholder
+	}
+	return s.finish(text)
+}
+
+// CloseWithError updates the message with the text written so far, marked as incomplete
+// so that it is not taken for a complete message
+func (s *slackStream) CloseWithError(err error) error {
+	return s.finish(s.text.String() + slackStreamCursor + fmt.Sprintf(slackStreamIncomplete, err))
+}
+
+// finish updates the message with the final text, waiting once for the rate limit if needed
+func (s *slackStream) finish(text string) error {
+	err := s.update(text)
+	var rateLimited *slack.RateLimitedError
+	if errors.As(err, &rateLimited) {
+		time.Sleep(rateLimited.RetryAfter)
+		err = s.update(text)
+	}
+	return err
+}
+
+func (s *slackStream) update(text string) error {
+	_, _, _, err := s.logger.client.UpdateMessage(
+		s.logger.channelID,
+		s.ts,
+		slack.MsgOptionText(text, false),
+		slack.MsgOptionParse(true),
+	)
+	return err
+}
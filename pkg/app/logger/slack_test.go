@@ -0,0 +1,78 @@
+package logger
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/slack-go/slack"
+)
+
+func TestSlackStreamClose(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		err      error
+		wantText string
+	}{
+		{
+			name:     "complete message",
+			text:     "The report",
+			wantText: "The report",
+		},
+		{
+			name:     "empty message",
+			wantText: slackStreamPlaceholder,
+		},
+		{
+			name:     "failed mid-stream",
+			text:     "The rep",
+			err:      errors.New("context deadline exceeded"),
+			wantText: "The rep ...\n\n:warning: The message is incomplete: context deadline exceeded",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var updated string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if err := r.ParseForm(); err != nil {
+					t.Error(err)
+					return
+				}
+				switch r.URL.Path {
+				case "/chat.postMessage":
+					_, _ = w.Write([]byte(`{"ok":true,"channel":"C1","ts":"1.000"}`))
+				case "/chat.update":
+					updated = r.FormValue("text")
+					_, _ = w.Write([]byte(`{"ok":true,"channel":"C1","ts":"1.000"}`))
+				default:
+					t.Errorf("unexpected Slack API call: %s", r.URL.Path)
+				}
+			}))
+			defer server.Close()
+
+			client := slack.New("token", slack.OptionAPIURL(server.URL+"/"))
+			stream, err := NewSlackLogger(client, "C1", "0.000").StartStream()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := stream.Write([]byte(tt.text)); err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.err != nil {
+				err = stream.CloseWithError(tt.err)
+			} else {
+				err = stream.Close()
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if updated != tt.wantText {
+				t.Errorf("message = %q, want %q", updated, tt.wantText)
+			}
+		})
+	}
+}
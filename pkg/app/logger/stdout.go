@@ -1,6 +1,10 @@
 package logger
 
-import "log"
+import (
+	"fmt"
+	"io"
+	"log"
+)
 
 // StdoutLogger is a logger that writes logs to standard output
 type StdoutLogger struct{}
@@ -13,3 +17,28 @@ func (l *StdoutLogger) Log(message string) error {
 	log.Println(message)
 	return nil
 }
+
+// StartStream returns a writer printing the text as it is written, without the log prefix
+func (l *StdoutLogger) StartStream() (Stream, error) {
+	return &stdoutStream{w: log.Writer()}, nil
+}
+
+type stdoutStream struct {
+	w io.Writer
+}
+
+func (s *stdoutStream) Write(p []byte) (int, error) {
+	return s.w.Write(p)
+}
+
+// Close ends the message with a line break
+func (s *stdoutStream) Close() error {
+	_, err := io.WriteString(s.w, "\n")
+	return err
+}
+
+// CloseWithError ends the message with a line telling that it is incomplete
+func (s *stdoutStream) CloseWithError(err error) error {
+	_, werr := fmt.Fprintf(s.w, "\n[incomplete: %v]\n", err)
+	return werr
+}
@@ -0,0 +1,157 @@
+package model
+
+import "sort"
+
+// Split splits the telemetry into chunks estimated to fit in the token budget each. Spans are split
+// by subtree: a subtree too large for a chunk is split into its root span and its child subtrees, and
+// small subtrees are packed together in depth-first order. Logs follow the chunk of their span, and
+// logs not attached to any span are chunked on their own. Spans the span tree does not reach are
+// chunked as units of their own, so every span is in a chunk. Warnings are not included in the chunks.
+func (t *Telemetry) Split(budget int) []*Telemetry {
+	logsBySpan := map[string]Logs{}
+	var unattached Logs
+	// Logs follow the first span with their span ID, so spans sharing an ID do not repeat them
+	logOwners := make(map[string]*Span, len(t.Spans))
+	for i := range t.Spans {
+		if _, ok := logOwners[t.Spans[i].SpanID]; !ok {
+			logOwners[t.Spans[i].SpanID] = &t.Spans[i]
+		}
+	}
+	for _, l := range t.Logs {
+		if _, ok := logOwners[l.SpanID]; l.SpanID != "" && ok {
+			logsBySpan[l.SpanID] = append(logsBySpan[l.SpanID], l)
+		} else {
+			unattached = append(unattached, l)
+		}
+	}
+
+	// spanLogs returns the logs chunked with the span
+	spanLogs := func(s *Span) Logs {
+		if logOwners[s.SpanID] != s {
+			return nil
+		}
+		return logsBySpan[s.SpanID]
+	}
+	// cost estimates the tokens of a span and its logs
+	cost := func(n *SpanNode) int {
+		tokens := estimateSpanTokens(*n.Span)
+		for _, l := range spanLogs(n.Span) {
+			tokens += estimateLogTokens(l)
+		}
+		return tokens
+	}
+	subtreeCosts := map[*SpanNode]int{}
+	var subtreeCost func(*SpanNode) int
+	subtreeCost = func(n *SpanNode) int {
+		if c, ok := subtreeCosts[n]; ok {
+			return c
+		}
+		c := cost(n)
+		for _, child := range n.Children {
+			c += subtreeCost(child)
+		}
+		subtreeCosts[n] = c
+		return c
+	}
+
+	// A unit is a set of spans that is kept in the same chunk
+	type unit struct {
+		nodes  []*SpanNode
+		tokens int
+	}
+	var units []unit
+	var collect func(n *SpanNode, nodes []*SpanNode) []*SpanNode
+	collect = func(n *SpanNode, nodes []*SpanNode) []*SpanNode {
+		nodes = append(nodes, n)
+		for _, c := range n.Children {
+			nodes = collect(c, nodes)
+		}
+		return nodes
+	}
+	var split func(*SpanNode)
+	split = func(n *SpanNode) {
+		if c := subtreeCost(n); c <= budget || len(n.Children) == 0 {
+			units = append(units, unit{nodes: collect(n, nil), tokens: c})
+			return
+		}
+		units = append(units, unit{nodes: []*SpanNode{n}, tokens: cost(n)})
+		for _, c := range n.Children {
+			split(c)
+		}
+	}
+	tree := t.Spans.Tree()
+	for _, n := range tree.Roots {
+		split(n)
+	}
+	for _, n := range tree.Orphans {
+		split(n)
+	}
+	// Spans the tree walk did not reach, such as spans reported more than once, become units of their own
+	visited := make(map[*Span]bool, len(t.Spans))
+	for _, u := range units {
+		for _, n := range u.nodes {
+			visited[n.Span] = true
+		}
+	}
+	for i := range t.Spans {
+		if !visited[&t.Spans[i]] {
+			n := &SpanNode{Span: &t.Spans[i]}
+			units = append(units, unit{nodes: []*SpanNode{n}, tokens: cost(n)})
+		}
+	}
+
+	var chunks []*Telemetry
+	current, tokens := &Telemetry{}, 0
+	flush := func() {
+		if len(current.Spans) > 0 || len(current.Logs) > 0 {
+			chunks = append(chunks, current)
+		}
+		current, tokens = &Telemetry{}, 0
+	}
+
+	for _, u := range units {
+		if tokens > 0 && tokens+u.tokens > budget {
+			flush()
+		}
+		for _, n := range u.nodes {
+			current.Spans = append(current.Spans, *n.Span)
+			current.Logs = append(current.Logs, spanLogs(n.Span)...)
+		}
+		tokens += u.tokens
+	}
+	flush()
+
+	for _, l := range unattached {
+		c := estimateLogTokens(l)
+		if tokens > 0 && tokens+c > budget {
+			flush()
+		}
+		current.Logs = append(current.Logs, l)
+		tokens += c
+	}
+	flush()
+
+	// Logs were grouped by span, so restore the chronological order
+	for _, c := range chunks {
+		sort.SliceStable(c.Logs, func(i, j int) bool {
+			return c.Logs[i].Timestamp.Before(c.Logs[j].Timestamp)
+		})
+	}
+	return chunks
+}
+
+func estimateSpanTokens(s Span) int {
+	csv, err := (&Spans{s}).AsCSV()
+	if err != nil {
+		return 0
+	}
+	return EstimateTokens(csv)
+}
+
+func estimateLogTokens(l Log) int {
+	csv, err := (&Logs{l}).AsCSV()
+	if err != nil {
+		return 0
+	}
+	return EstimateTokens(csv)
+}
@@ -0,0 +1,118 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTelemetrySplit(t *testing.T) {
+	// r0 has two children: a0, which has the child a1, and b0
+	spans := Spans{
+		testSpan("r0", "", 0, 100),
+		testSpan("a0", "r0", 10, 50),
+		testSpan("a1", "a0", 20, 40),
+		testSpan("b0", "r0", 60, 90),
+	}
+	a1Log := Log{Timestamp: testTraceStart.Add(30 * time.Millisecond), SpanID: "a1", Message: "a1 log"}
+	r0Log := Log{Timestamp: testTraceStart.Add(5 * time.Millisecond), SpanID: "r0", Message: "r0 log"}
+	unattached := Log{Timestamp: testTraceStart, SpanID: "missing", Message: "unattached log"}
+
+	tests := []struct {
+		name   string
+		budget int
+		want   [][]string
+	}{
+		{
+			name:   "unattached logs chunked on their own",
+			budget: 100000,
+			want:   [][]string{{"r0", "a0", "a1", "b0", "r0 log", "a1 log"}, {"unattached log"}},
+		},
+		{
+			name:   "subtree kept together",
+			budget: estimateSpanTokens(spans[1]) + estimateSpanTokens(spans[2]) + estimateLogTokens(a1Log),
+			want:   [][]string{{"r0", "r0 log"}, {"a0", "a1", "a1 log"}, {"b0"}, {"unattached log"}},
+		},
+		{
+			name:   "every span in its own chunk",
+			budget: 0,
+			want:   [][]string{{"r0", "r0 log"}, {"a0"}, {"a1", "a1 log"}, {"b0"}, {"unattached log"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			telemetry := &Telemetry{
+				Spans:    spans,
+				Logs:     Logs{a1Log, r0Log, unattached},
+				Warnings: []Warning{{Source: "logs", Message: "partial result"}},
+			}
+
+			var got [][]string
+			for _, c := range telemetry.Split(tt.budget) {
+				if len(c.Warnings) > 0 {
+					t.Errorf("chunk has warnings %v, want none", c.Warnings)
+				}
+				got = append(got, append(spanIDs(c.Spans), logMessages(c.Logs)...))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Split() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTelemetrySplitKeepsEverySpan(t *testing.T) {
+	unnamed := testSpan("", "r0", 10, 20)
+	sharedLog := Log{Timestamp: testTraceStart.Add(15 * time.Millisecond), SpanID: "a0", Message: "a0 log"}
+
+	tests := []struct {
+		name  string
+		spans Spans
+		logs  Logs
+		want  [][]string
+	}{
+		{
+			name:  "spans without an ID",
+			spans: Spans{testSpan("r0", "", 0, 100), unnamed, unnamed},
+			want:  [][]string{{"r0", "", ""}},
+		},
+		{
+			name:  "span reported more than once",
+			spans: Spans{testSpan("r0", "", 0, 100), testSpan("a0", "r0", 10, 50), testSpan("a0", "r0", 10, 50)},
+			logs:  Logs{sharedLog},
+			want:  [][]string{{"r0", "a0", "a0", "a0 log"}},
+		},
+		{
+			name:  "different spans with the same ID",
+			spans: Spans{testSpan("r0", "", 0, 100), testSpan("a0", "r0", 10, 50), testSpan("a0", "r0", 20, 40)},
+			logs:  Logs{sharedLog},
+			want:  [][]string{{"r0", "a0", "a0", "a0 log"}},
+		},
+		{
+			name:  "cycle of parent span IDs",
+			spans: Spans{testSpan("r0", "", 0, 100), testSpan("a0", "b0", 10, 50), testSpan("b0", "a0", 20, 40)},
+			want:  [][]string{{"r0", "a0", "b0"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			telemetry := &Telemetry{Spans: tt.spans, Logs: tt.logs}
+
+			var got [][]string
+			for _, c := range telemetry.Split(100000) {
+				got = append(got, append(spanIDs(c.Spans), logMessages(c.Logs)...))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Split() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTelemetrySplitEmpty(t *testing.T) {
+	if chunks := (&Telemetry{}).Split(100); len(chunks) != 0 {
+		t.Errorf("Split() returned %d chunks, want 0", len(chunks))
+	}
+}
@@ -0,0 +1,543 @@
+package model
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// compactionWarningSource is the warning source of the elision records
+	compactionWarningSource = "compaction"
+	// collapseThreshold is the minimum number of repeated sibling spans to collapse
+	collapseThreshold = 5
+	// maxValueLength is the maximum length of attribute values and log messages after truncation
+	maxValueLength = 512
+	// maxListedAttributes is the maximum number of attributes listed in an elision record
+	maxListedAttributes = 20
+)
+
+// lowValueAttributePrefixes are attributes describing the instrumentation rather than the request,
+// which are dropped first when the telemetry does not fit in the token budget
+var lowValueAttributePrefixes = []string{
+	"process.",
+	"telemetry.sdk.",
+	"telemetry.auto.",
+	"otel.library.",
+	"otel.scope.",
+	"instrumentation.",
+	"agent.",
+	"nr.",
+	"newRelic.",
+	"entity.guid",
+	"guid",
+	"sampled",
+	"priority",
+	"thread.",
+	"host.arch",
+	"os.",
+	"container.id",
+	"k8s.pod.uid",
+}
+
+// debugLogLevels are the log levels sampled when the telemetry does not fit in the token budget
+var debugLogLevels = map[string]bool{
+	"DEBUG":   true,
+	"TRACE":   true,
+	"FINE":    true,
+	"FINER":   true,
+	"FINEST":  true,
+	"VERBOSE": true,
+}
+
+// EstimateTokens roughly estimates the token count of the text
+func EstimateTokens(s string) int {
+	return len([]rune(s)) / 3
+}
+
+// compactionStep reduces the telemetry and returns the descriptions of what was elided.
+// excess is the estimated number of tokens over the budget.
+type compactionStep func(t *Telemetry, excess int) []string
+
+// reductionSteps reduce the telemetry while keeping every span and every log above DEBUG level,
+// either as is or as part of an aggregated row
+var reductionSteps = []compactionStep{
+	dropLowValueAttributes,
+	dropConstantAttributes,
+	collapseRepeatedSiblings,
+	sampleDebugLogs,
+	truncateLongValues,
+}
+
+// droppingSteps drop spans and logs as the last resort
+var droppingSteps = []compactionStep{
+	dropShortSpans,
+	sampleLogs,
+}
+
+// Compact returns a copy of the telemetry reduced to fit in the token budget. The following steps
+// are applied in order until the estimated token count is within the budget:
+//
+//  1. drop attributes describing the instrumentation (SDK, agent and process metadata)
+//  2. drop attributes with the same value in all spans or logs
+//  3. collapse repeated sibling spans (e.g., N+1 queries) into aggregated rows
+//  4. sample DEBUG logs
+//  5. truncate long attribute values and log messages
+//  6. drop the shortest successful spans
+//  7. sample non-error logs
+//
+// What was elided is recorded in the warnings so that the prompt can tell the model.
+// The original telemetry is left unchanged.
+func (t *Telemetry) Compact(budget int) (*Telemetry, error) {
+	compacted, excess, err := t.compact(budget, append(append([]compactionStep{}, reductionSteps...), droppingSteps...))
+	if err != nil {
+		return nil, err
+	}
+
+	if excess > 0 {
+		compacted.Warnings = append(compacted.Warnings, Warning{
+			Source:  compactionWarningSource,
+			Message: fmt.Sprintf("the telemetry still exceeds the token budget of %d by about %d tokens", budget, excess),
+		})
+	}
+	return compacted, nil
+}
+
+// Reduce is like Compact but stops before dropping spans and logs (steps 6 and 7).
+// It reports whether the reduced telemetry fits in the token budget.
+func (t *Telemetry) Reduce(budget int) (*Telemetry, bool, error) {
+	reduced, excess, err := t.compact(budget, reductionSteps)
+	if err != nil {
+		return nil, false, err
+	}
+	return reduced, excess <= 0, nil
+}
+
+// compact applies the steps until the telemetry fits in the budget
+// and returns the estimated number of tokens still over the budget
+func (t *Telemetry) compact(budget int, steps []compactionStep) (*Telemetry, int, error) {
+	compacted := t.clone()
+
+	excess, err := compacted.excessTokens(budget)
+	if err != nil || excess <= 0 {
+		return compacted, excess, err
+	}
+
+	for _, step := range steps {
+		for _, elided := range step(compacted, excess) {
+			compacted.Warnings = append(compacted.Warnings, Warning{Source: compactionWarningSource, Message: elided})
+		}
+
+		excess, err = compacted.excessTokens(budget)
+		if err != nil {
+			return nil, 0, err
+		}
+		if excess <= 0 {
+			break
+		}
+	}
+
+	return compacted, excess, nil
+}
+
+// excessTokens returns the estimated number of tokens over the budget
+func (t *Telemetry) excessTokens(budget int) (int, error) {
+	tokens, err := t.RoughTokenEstimate()
+	if err != nil {
+		return 0, err
+	}
+	return tokens - budget, nil
+}
+
+// clone returns a copy of the telemetry whose spans, logs and attributes can be modified
+func (t *Telemetry) clone() *Telemetry {
+	c := &Telemetry{
+		Spans:    make(Spans, len(t.Spans)),
+		Logs:     make(Logs, len(t.Logs)),
+		Warnings: append([]Warning{}, t.Warnings...),
+	}
+	for i, s := range t.Spans {
+		s.Attributes = maps.Clone(s.Attributes)
+		c.Spans[i] = s
+	}
+	for i, l := range t.Logs {
+		l.Attributes = maps.Clone(l.Attributes)
+		c.Logs[i] = l
+	}
+	return c
+}
+
+func dropLowValueAttributes(t *Telemetry, excess int) []string {
+	dropped := map[string]bool{}
+	drop := func(attrs map[string]any) {
+		for k := range attrs {
+			for _, prefix := range lowValueAttributePrefixes {
+				if strings.HasPrefix(k, prefix) {
+					delete(attrs, k)
+					dropped[k] = true
+					break
+				}
+			}
+		}
+	}
+	for _, s := range t.Spans {
+		drop(s.Attributes)
+	}
+	for _, l := range t.Logs {
+		drop(l.Attributes)
+	}
+
+	if len(dropped) == 0 {
+		return nil
+	}
+	return []string{fmt.Sprintf("removed %d attributes describing the instrumentation: %s",
+		len(dropped), listKeys(sortedKeys(dropped)))}
+}
+
+func dropConstantAttributes(t *Telemetry, excess int) []string {
+	var elided []string
+
+	spanAttrs := make([]map[string]any, len(t.Spans))
+	for i, s := range t.Spans {
+		spanAttrs[i] = s.Attributes
+	}
+	if constants := removeConstantAttributes(spanAttrs); len(constants) > 0 {
+		elided = append(elided, "removed attributes with the same value in all spans: "+listKeys(constants))
+	}
+
+	logAttrs := make([]map[string]any, len(t.Logs))
+	for i, l := range t.Logs {
+		logAttrs[i] = l.Attributes
+	}
+	if constants := removeConstantAttributes(logAttrs); len(constants) > 0 {
+		elided = append(elided, "removed attributes with the same value in all logs: "+listKeys(constants))
+	}
+
+	return elided
+}
+
+// removeConstantAttributes removes the attributes present with the same value in all the rows
+// and returns them as key=value
+func removeConstantAttributes(rows []map[string]any) []string {
+	if len(rows) < 2 {
+		return nil
+	}
+
+	var constants []string
+	for k, v := range rows[0] {
+		value := formatCSVValue(v)
+		constant := true
+		for _, row := range rows[1:] {
+			other, ok := row[k]
+			if !ok || formatCSVValue(other) != value {
+				constant = false
+				break
+			}
+		}
+		if constant {
+			constants = append(constants, k+"="+truncate(value, 100))
+		}
+	}
+	sort.Strings(constants)
+
+	for _, c := range constants {
+		k, _, _ := strings.Cut(c, "=")
+		for _, row := range rows {
+			delete(row, k)
+		}
+	}
+	return constants
+}
+
+// collapseRepeatedSiblings replaces successful sibling spans sharing the same service and name
+// (e.g., queries issued in a loop) with the first span annotated with the aggregated durations.
+// The children of the other spans are removed along with them.
+func collapseRepeatedSiblings(t *Telemetry, excess int) []string {
+	tree := t.Spans.Tree()
+
+	removed := map[*Span]bool{}
+	var removeSubtree func(*SpanNode)
+	removeSubtree = func(n *SpanNode) {
+		removed[n.Span] = true
+		for _, c := range n.Children {
+			removeSubtree(c)
+		}
+	}
+
+	var examples []string
+	groups, collapsed := 0, 0
+	for _, node := range tree.Nodes() {
+		if removed[node.Span] {
+			continue
+		}
+		siblings := map[string][]*SpanNode{}
+		var order []string
+		for _, c := range node.Children {
+			if c.Span.IsError() {
+				continue
+			}
+			key := c.Span.ServiceName + "\x00" + c.Span.Name
+			if _, ok := siblings[key]; !ok {
+				order = append(order, key)
+			}
+			siblings[key] = append(siblings[key], c)
+		}
+
+		for _, key := range order {
+			group := siblings[key]
+			if len(group) < collapseThreshold {
+				continue
+			}
+
+			first := group[0].Span
+			var total, minDuration, maxDuration time.Duration
+			end := first.EndTime()
+			for i, n := range group {
+				d := n.Span.Duration
+				total += d
+				if i == 0 || d < minDuration {
+					minDuration = d
+				}
+				if d > maxDuration {
+					maxDuration = d
+				}
+				if n.Span.EndTime().After(end) {
+					end = n.Span.EndTime()
+				}
+				if i > 0 {
+					removeSubtree(n)
+				}
+			}
+
+			// The aggregated row covers the wall-clock time from the first start to the last end
+			first.Duration = end.Sub(first.StartTime)
+			if first.Attributes == nil {
+				first.Attributes = map[string]any{}
+			}
+			first.Attributes["collapsed.count"] = len(group)
+			first.Attributes["collapsed.total_duration_ms"] = formatMillis(total)
+			first.Attributes["collapsed.min_duration_ms"] = formatMillis(minDuration)
+			first.Attributes["collapsed.max_duration_ms"] = formatMillis(maxDuration)
+
+			groups++
+			collapsed += len(group) - 1
+			if len(examples) < 3 {
+				examples = append(examples, fmt.Sprintf("%q x%d", first.Name, len(group)))
+			}
+		}
+	}
+
+	if groups == 0 {
+		return nil
+	}
+
+	kept := make(Spans, 0, len(t.Spans)-len(removed))
+	for i := range t.Spans {
+		if !removed[&t.Spans[i]] {
+			kept = append(kept, t.Spans[i])
+		}
+	}
+	descendants := len(removed) - collapsed
+	t.Spans = kept
+
+	msg := fmt.Sprintf("collapsed %d repeated sibling spans into %d aggregated rows with collapsed.* attributes (e.g., %s)",
+		collapsed+groups, groups, strings.Join(examples, ", "))
+	if descendants > 0 {
+		msg += fmt.Sprintf("; %d child spans of the collapsed spans were removed", descendants)
+	}
+	return []string{msg}
+}
+
+func sampleDebugLogs(t *Telemetry, excess int) []string {
+	var debugLogs Logs
+	for _, l := range t.Logs {
+		if debugLogLevels[strings.ToUpper(l.level())] {
+			debugLogs = append(debugLogs, l)
+		}
+	}
+	if len(debugLogs) == 0 {
+		return nil
+	}
+
+	csv, err := debugLogs.AsCSV()
+	if err != nil {
+		return nil
+	}
+	every := samplingInterval(EstimateTokens(csv), excess)
+
+	kept, n, dropped := t.Logs[:0], 0, 0
+	for _, l := range t.Logs {
+		if debugLogLevels[strings.ToUpper(l.level())] {
+			n++
+			if every == 0 || (n-1)%every != 0 {
+				dropped++
+				continue
+			}
+		}
+		kept = append(kept, l)
+	}
+	t.Logs = kept
+
+	if every == 0 {
+		return []string{fmt.Sprintf("removed all %d DEBUG logs", dropped)}
+	}
+	return []string{fmt.Sprintf("sampled DEBUG logs keeping 1 in %d (%d of %d removed)", every, dropped, len(debugLogs))}
+}
+
+func truncateLongValues(t *Telemetry, excess int) []string {
+	truncated := 0
+	truncateAttrs := func(attrs map[string]any) {
+		for k, v := range attrs {
+			s, ok := v.(string)
+			if !ok {
+				if s = formatCSVValue(v); len(s) <= maxValueLength {
+					continue
+				}
+			}
+			if len([]rune(s)) > maxValueLength {
+				attrs[k] = truncate(s, maxValueLength)
+				truncated++
+			}
+		}
+	}
+
+	for _, s := range t.Spans {
+		truncateAttrs(s.Attributes)
+	}
+	for i := range t.Logs {
+		if len([]rune(t.Logs[i].Message)) > maxValueLength {
+			t.Logs[i].Message = truncate(t.Logs[i].Message, maxValueLength)
+			truncated++
+		}
+		truncateAttrs(t.Logs[i].Attributes)
+	}
+
+	if truncated == 0 {
+		return nil
+	}
+	return []string{fmt.Sprintf("truncated %d attribute values and log messages longer than %d characters", truncated, maxValueLength)}
+}
+
+// dropShortSpans removes the shortest successful spans, which contribute the least to the latency
+func dropShortSpans(t *Telemetry, excess int) []string {
+	var candidates []int
+	for i := range t.Spans {
+		if !t.Spans[i].IsError() && t.Spans[i].ParentSpanID != "" {
+			candidates = append(candidates, i)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	tokens, err := t.Spans.AsCSV()
+	if err != nil {
+		return nil
+	}
+	perSpan := math.Max(1, float64(EstimateTokens(tokens))/float64(len(t.Spans)))
+	n := min(len(candidates), int(math.Ceil(float64(excess)/perSpan)))
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return t.Spans[candidates[i]].Duration < t.Spans[candidates[j]].Duration
+	})
+	drop := map[int]bool{}
+	for _, i := range candidates[:n] {
+		drop[i] = true
+	}
+	threshold := t.Spans[candidates[n-1]].Duration
+
+	kept := make(Spans, 0, len(t.Spans)-n)
+	for i, s := range t.Spans {
+		if !drop[i] {
+			kept = append(kept, s)
+		}
+	}
+	t.Spans = kept
+
+	return []string{fmt.Sprintf("removed the %d shortest successful spans (%sms or shorter); some spans may reference missing parents",
+		n, formatMillis(threshold))}
+}
+
+// sampleLogs samples the logs below ERROR level
+func sampleLogs(t *Telemetry, excess int) []string {
+	var candidates Logs
+	for _, l := range t.Logs {
+		if !l.IsError() {
+			candidates = append(candidates, l)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	csv, err := candidates.AsCSV()
+	if err != nil {
+		return nil
+	}
+	every := samplingInterval(EstimateTokens(csv), excess)
+
+	kept, n, dropped := t.Logs[:0], 0, 0
+	for _, l := range t.Logs {
+		if !l.IsError() {
+			n++
+			if every == 0 || (n-1)%every != 0 {
+				dropped++
+				continue
+			}
+		}
+		kept = append(kept, l)
+	}
+	t.Logs = kept
+
+	if every == 0 {
+		return []string{fmt.Sprintf("removed all %d logs below ERROR level", dropped)}
+	}
+	return []string{fmt.Sprintf("sampled logs below ERROR level keeping 1 in %d (%d of %d removed)", every, dropped, len(candidates))}
+}
+
+// samplingInterval returns n to keep 1 in n rows so that excess tokens are removed from rows
+// estimated at tokens in total, or 0 when all the rows need to be removed
+func samplingInterval(tokens, excess int) int {
+	if excess >= tokens {
+		return 0
+	}
+	return int(math.Ceil(float64(tokens) / float64(tokens-excess)))
+}
+
+// level returns the log level from the first level attribute found
+func (l *Log) level() string {
+	for _, k := range logLevelKeys {
+		if level, ok := l.Attributes[k].(string); ok {
+			return level
+		}
+	}
+	return ""
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n]) + "...(truncated)"
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// listKeys joins the keys, abbreviating long lists
+func listKeys(keys []string) string {
+	if len(keys) > maxListedAttributes {
+		return strings.Join(keys[:maxListedAttributes], ", ") + fmt.Sprintf(" and %d more", len(keys)-maxListedAttributes)
+	}
+	return strings.Join(keys, ", ")
+}
@@ -0,0 +1,274 @@
+package model
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testTelemetry builds a trace with a root span, 6 repeated queries, a failing span
+// and logs at INFO, DEBUG and ERROR levels
+func testTelemetry() *Telemetry {
+	spans := Spans{testSpan("root", "", 0, 100)}
+	for i, id := range []string{"q1", "q2", "q3", "q4", "q5", "q6"} {
+		s := testSpan(id, "root", 10+i*10, 15+i*10)
+		s.Name = "SELECT orders"
+		s.Attributes = map[string]any{"process.pid": 42, "db.statement": id}
+		spans = append(spans, s)
+	}
+	failing := testSpan("failing", "root", 80, 90)
+	failing.Status = SpanStatusError
+	spans = append(spans, failing)
+
+	return &Telemetry{
+		Spans: spans,
+		Logs: Logs{
+			{Message: "request received", Attributes: map[string]any{"level": "INFO"}},
+			{Message: "cache miss", Attributes: map[string]any{"level": "DEBUG"}},
+			{Message: "query failed", Attributes: map[string]any{"level": "ERROR"}},
+		},
+		Warnings: []Warning{{Source: "logs", Message: "partial result"}},
+	}
+}
+
+func TestTelemetryCompact(t *testing.T) {
+	tests := []struct {
+		name         string
+		telemetry    func() *Telemetry
+		budget       int
+		wantSpans    []string
+		wantLogs     []string
+		wantWarnings []string
+	}{
+		{
+			name:         "fits in the budget",
+			telemetry:    testTelemetry,
+			budget:       100000,
+			wantSpans:    []string{"root", "q1", "q2", "q3", "q4", "q5", "q6", "failing"},
+			wantLogs:     []string{"request received", "cache miss", "query failed"},
+			wantWarnings: []string{"partial result"},
+		},
+		{
+			name:      "zero budget keeps the root span and the errors",
+			telemetry: testTelemetry,
+			budget:    0,
+			wantSpans: []string{"root", "failing"},
+			wantLogs:  []string{"query failed"},
+			wantWarnings: []string{
+				"partial result",
+				"removed 1 attributes describing the instrumentation: process.pid",
+				`collapsed 6 repeated sibling spans into 1 aggregated rows with collapsed.* attributes (e.g., "SELECT orders" x6)`,
+				"removed all 1 DEBUG logs",
+				"removed the 1 shortest successful spans (55ms or shorter)",
+				"removed all 1 logs below ERROR level",
+				"the telemetry still exceeds the token budget of 0",
+			},
+		},
+		{
+			name: "only errors are never dropped",
+			telemetry: func() *Telemetry {
+				failing := testSpan("failing", "root", 0, 10)
+				failing.Status = SpanStatusError
+				failing.Attributes = map[string]any{"exception.message": strings.Repeat("x", 1000)}
+				return &Telemetry{
+					Spans: Spans{failing},
+					Logs:  Logs{{Message: strings.Repeat("y", 1000), Attributes: map[string]any{"level": "ERROR"}}},
+				}
+			},
+			budget:    0,
+			wantSpans: []string{"failing"},
+			wantLogs:  []string{strings.Repeat("y", maxValueLength) + "...(truncated)"},
+			wantWarnings: []string{
+				"truncated 2 attribute values and log messages longer than 512 characters",
+				"the telemetry still exceeds the token budget of 0",
+			},
+		},
+		{
+			name:         "empty telemetry over the budget with the CSV headers",
+			telemetry:    func() *Telemetry { return &Telemetry{} },
+			budget:       0,
+			wantSpans:    []string{},
+			wantLogs:     []string{},
+			wantWarnings: []string{"the telemetry still exceeds the token budget of 0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			telemetry := tt.telemetry()
+			original := tt.telemetry()
+
+			compacted, err := telemetry.Compact(tt.budget)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := spanIDs(compacted.Spans); !reflect.DeepEqual(got, tt.wantSpans) {
+				t.Errorf("spans = %v, want %v", got, tt.wantSpans)
+			}
+			if got := logMessages(compacted.Logs); !reflect.DeepEqual(got, tt.wantLogs) {
+				t.Errorf("logs = %v, want %v", got, tt.wantLogs)
+			}
+			if len(compacted.Warnings) != len(tt.wantWarnings) {
+				t.Fatalf("warnings = %v, want %v", compacted.Warnings, tt.wantWarnings)
+			}
+			for i, w := range compacted.Warnings {
+				if !strings.HasPrefix(w.Message, tt.wantWarnings[i]) {
+					t.Errorf("warning %d = %q, want prefix %q", i, w.Message, tt.wantWarnings[i])
+				}
+			}
+			if !reflect.DeepEqual(telemetry, original) {
+				t.Error("Compact() modified the original telemetry")
+			}
+		})
+	}
+}
+
+func TestTelemetryReduce(t *testing.T) {
+	tests := []struct {
+		name      string
+		budget    int
+		wantSpans []string
+		wantLogs  []string
+		wantFits  bool
+	}{
+		{
+			name:      "fits in the budget",
+			budget:    100000,
+			wantSpans: []string{"root", "q1", "q2", "q3", "q4", "q5", "q6", "failing"},
+			wantLogs:  []string{"request received", "cache miss", "query failed"},
+			wantFits:  true,
+		},
+		{
+			name:      "zero budget keeps every span and non-DEBUG log",
+			budget:    0,
+			wantSpans: []string{"root", "q1", "failing"},
+			wantLogs:  []string{"request received", "query failed"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reduced, fits, err := testTelemetry().Reduce(tt.budget)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fits != tt.wantFits {
+				t.Errorf("fits = %v, want %v", fits, tt.wantFits)
+			}
+			if got := spanIDs(reduced.Spans); !reflect.DeepEqual(got, tt.wantSpans) {
+				t.Errorf("spans = %v, want %v", got, tt.wantSpans)
+			}
+			if got := logMessages(reduced.Logs); !reflect.DeepEqual(got, tt.wantLogs) {
+				t.Errorf("logs = %v, want %v", got, tt.wantLogs)
+			}
+		})
+	}
+}
+
+func TestCollapseRepeatedSiblings(t *testing.T) {
+	telemetry := testTelemetry()
+	collapseRepeatedSiblings(telemetry, 0)
+
+	if got := spanIDs(telemetry.Spans); !reflect.DeepEqual(got, []string{"root", "q1", "failing"}) {
+		t.Fatalf("spans = %v, want root, q1, failing", got)
+	}
+	collapsed := telemetry.Spans[1]
+	want := map[string]any{
+		"collapsed.count":             6,
+		"collapsed.total_duration_ms": "30",
+		"collapsed.min_duration_ms":   "5",
+		"collapsed.max_duration_ms":   "5",
+	}
+	for k, v := range want {
+		if collapsed.Attributes[k] != v {
+			t.Errorf("%s = %v, want %v", k, collapsed.Attributes[k], v)
+		}
+	}
+	// The aggregated row covers the first start to the last end
+	if got := formatMillis(collapsed.Duration); got != "55" {
+		t.Errorf("duration = %sms, want 55ms", got)
+	}
+}
+
+func TestRemoveConstantAttributes(t *testing.T) {
+	tests := []struct {
+		name string
+		rows []map[string]any
+		want []string
+		left []map[string]any
+	}{
+		{
+			name: "single row is kept",
+			rows: []map[string]any{{"a": 1}},
+			left: []map[string]any{{"a": 1}},
+		},
+		{
+			name: "same value in all rows",
+			rows: []map[string]any{{"a": 1, "b": "x"}, {"a": 1, "b": "y"}},
+			want: []string{"a=1"},
+			left: []map[string]any{{"b": "x"}, {"b": "y"}},
+		},
+		{
+			name: "missing in a row",
+			rows: []map[string]any{{"a": 1}, {}},
+			left: []map[string]any{{"a": 1}, {}},
+		},
+		{
+			name: "same value in different types",
+			rows: []map[string]any{{"a": 1}, {"a": float64(1)}},
+			want: []string{"a=1"},
+			left: []map[string]any{{}, {}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := removeConstantAttributes(tt.rows); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("removeConstantAttributes() = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(tt.rows, tt.left) {
+				t.Errorf("rows = %v, want %v", tt.rows, tt.left)
+			}
+		})
+	}
+}
+
+func TestSamplingInterval(t *testing.T) {
+	tests := []struct {
+		name   string
+		tokens int
+		excess int
+		want   int
+	}{
+		{name: "remove half", tokens: 100, excess: 50, want: 2},
+		{name: "remove a little", tokens: 100, excess: 1, want: 2},
+		{name: "remove most", tokens: 100, excess: 90, want: 10},
+		{name: "remove all", tokens: 100, excess: 100, want: 0},
+		{name: "more than all", tokens: 100, excess: 150, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := samplingInterval(tt.tokens, tt.excess); got != tt.want {
+				t.Errorf("samplingInterval(%d, %d) = %d, want %d", tt.tokens, tt.excess, got, tt.want)
+			}
+		})
+	}
+}
+
+func spanIDs(spans Spans) []string {
+	ids := []string{}
+	for _, s := range spans {
+		ids = append(ids, s.SpanID)
+	}
+	return ids
+}
+
+func logMessages(logs Logs) []string {
+	messages := []string{}
+	for _, l := range logs {
+		messages = append(messages, l.Message)
+	}
+	return messages
+}
@@ -0,0 +1,118 @@
+package model
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// priorityAttributeKeys are attributes placed before the others in CSV output
+// as they matter most for the analysis
+var priorityAttributeKeys = concatKeys(
+	logLevelKeys,
+	[]string{"error"},
+	spanErrorKeys,
+	[]string{
+		"http.method",
+		"http.request.method",
+		"http.route",
+		"http.url",
+		"url.full",
+	},
+	spanHTTPStatusKeys,
+	[]string{
+		"db.system",
+		"db.operation",
+		"db.statement",
+		"db.query.text",
+		"rpc.service",
+		"rpc.method",
+		"messaging.system",
+		"messaging.destination.name",
+	},
+)
+
+// attributeColumns returns the attribute keys ordered by priority, followed by the rest in sorted order
+func attributeColumns(keys map[string]bool) []string {
+	columns := make([]string, 0, len(keys))
+	seen := make(map[string]bool, len(keys))
+	for _, k := range priorityAttributeKeys {
+		if keys[k] && !seen[k] {
+			columns = append(columns, k)
+			seen[k] = true
+		}
+	}
+
+	var rest []string
+	for k := range keys {
+		if !seen[k] {
+			rest = append(rest, k)
+		}
+	}
+	sort.Strings(rest)
+
+	return append(columns, rest...)
+}
+
+// writeCSV encodes the header and the rows as RFC 4180 CSV,
+// quoting values that contain commas, quotes or line breaks
+func writeCSV(headers []string, rows [][]string) (string, error) {
+	var buf strings.Builder
+	w := csv.NewWriter(&buf)
+	if err := w.Write(headers); err != nil {
+		return "", err
+	}
+	if err := w.WriteAll(rows); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// formatCSVValue formats a value for a CSV cell. Numbers are never written in exponent form,
+// times are written in RFC 3339 and nested values (maps and slices) are written as JSON.
+func formatCSVValue(v any) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case bool:
+		return strconv.FormatBool(val)
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(val), 'f', -1, 32)
+	case int, int32, int64, uint, uint32, uint64, json.Number:
+		return fmt.Sprintf("%v", val)
+	case time.Time:
+		if val.IsZero() {
+			return ""
+		}
+		return val.Format(time.RFC3339Nano)
+	case time.Duration:
+		return formatMillis(val)
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(b)
+}
+
+// formatMillis formats the duration in fractional milliseconds
+func formatMillis(d time.Duration) string {
+	return strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', -1, 64)
+}
+
+func concatKeys(lists ...[]string) []string {
+	var keys []string
+	for _, l := range lists {
+		keys = append(keys, l...)
+	}
+	return keys
+}
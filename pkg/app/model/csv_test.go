@@ -0,0 +1,185 @@
+package model
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatCSVValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		value any
+		want  string
+	}{
+		{name: "nil", value: nil, want: ""},
+		{name: "string", value: "SELECT a, b FROM t", want: "SELECT a, b FROM t"},
+		{name: "bool", value: true, want: "true"},
+		{name: "integral float", value: float64(500), want: "500"},
+		{name: "large float without exponent", value: 1.5e21, want: "1500000000000000000000"},
+		{name: "small float without exponent", value: 0.000001, want: "0.000001"},
+		{name: "float32", value: float32(0.1), want: "0.1"},
+		{name: "int", value: 42, want: "42"},
+		{name: "int64", value: int64(-7), want: "-7"},
+		{name: "json number", value: json.Number("12345678901234567890"), want: "12345678901234567890"},
+		{name: "time", value: time.Date(2024, 1, 1, 0, 0, 0, 500, time.UTC), want: "2024-01-01T00:00:00.0000005Z"},
+		{name: "zero time", value: time.Time{}, want: ""},
+		{name: "duration", value: 1500 * time.Microsecond, want: "1.5"},
+		{name: "slice", value: []string{"a", "b"}, want: `["a","b"]`},
+		{name: "map with sorted keys", value: map[string]any{"b": 1, "a": "x"}, want: `{"a":"x","b":1}`},
+		{name: "nested values", value: []map[string]any{{"event": "error"}}, want: `[{"event":"error"}]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatCSVValue(tt.value); got != tt.want {
+				t.Errorf("formatCSVValue() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAttributeColumns(t *testing.T) {
+	tests := []struct {
+		name string
+		keys []string
+		want []string
+	}{
+		{
+			name: "no keys",
+			want: []string{},
+		},
+		{
+			name: "sorted without priority keys",
+			keys: []string{"zeta", "alpha", "mid"},
+			want: []string{"alpha", "mid", "zeta"},
+		},
+		{
+			name: "priority keys first in priority order",
+			keys: []string{"custom", "db.statement", "http.method", "level", "error.message"},
+			want: []string{"level", "error.message", "http.method", "db.statement", "custom"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys := map[string]bool{}
+			for _, k := range tt.keys {
+				keys[k] = true
+			}
+			if got := attributeColumns(keys); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("attributeColumns() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSpansAsCSV(t *testing.T) {
+	tests := []struct {
+		name  string
+		spans Spans
+		want  [][]string
+	}{
+		{
+			name: "no spans",
+			want: [][]string{spanCSVHeaders},
+		},
+		{
+			name: "values with commas, quotes and line breaks",
+			spans: Spans{
+				{
+					TraceID:   "4bf92f3577b34da6a3ce929d0e0e4736",
+					SpanID:    "a3ce929d0e0e4736",
+					Name:      "SELECT orders",
+					Kind:      SpanKindClient,
+					StartTime: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+					Duration:  120 * time.Millisecond,
+					Status:    SpanStatusError,
+					Attributes: map[string]any{
+						"db.statement": "SELECT id, \"name\"\nFROM orders",
+						"retries":      float64(2),
+					},
+				},
+				{
+					SpanID: "00f067aa0ba902b7",
+					Attributes: map[string]any{
+						"tags": []string{"a", "b"},
+					},
+				},
+			},
+			want: [][]string{
+				append(append([]string{}, spanCSVHeaders...), "attributes.db.statement", "attributes.retries", "attributes.tags"),
+				{"4bf92f3577b34da6a3ce929d0e0e4736", "a3ce929d0e0e4736", "", "", "SELECT orders", "client", "2024-01-01T00:00:00Z", "120", "error", "", "SELECT id, \"name\"\nFROM orders", "2", ""},
+				{"", "00f067aa0ba902b7", "", "", "", "", "", "0", "", "", "", "", `["a","b"]`},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := tt.spans.AsCSV()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := readCSV(t, out); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("AsCSV() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogsAsCSV(t *testing.T) {
+	tests := []struct {
+		name string
+		logs Logs
+		want [][]string
+	}{
+		{
+			name: "no logs",
+			want: [][]string{logCSVHeaders},
+		},
+		{
+			name: "values with commas, quotes and line breaks",
+			logs: Logs{
+				{
+					Timestamp: time.Date(2024, 1, 1, 0, 0, 1, 0, time.UTC),
+					TraceID:   "4bf92f3577b34da6a3ce929d0e0e4736",
+					Message:   "failed to load order 1, retrying: \"timeout\"\npanic: boom",
+					Attributes: map[string]any{
+						"service": "orders",
+						"level":   "ERROR",
+					},
+				},
+			},
+			want: [][]string{
+				append(append([]string{}, logCSVHeaders...), "attributes.level", "attributes.service"),
+				{"2024-01-01T00:00:01Z", "4bf92f3577b34da6a3ce929d0e0e4736", "", "failed to load order 1, retrying: \"timeout\"\npanic: boom", "ERROR", "orders"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := tt.logs.AsCSV()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := readCSV(t, out); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("AsCSV() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// readCSV parses the CSV output so that the tests check the columns as an LLM or a CSV reader sees them
+func readCSV(t *testing.T, s string) [][]string {
+	t.Helper()
+	records, err := csv.NewReader(strings.NewReader(s)).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid CSV %q: %v", s, err)
+	}
+	return records
+}
@@ -1,12 +1,8 @@
 package model
 
 import (
-	"encoding/json"
-	"fmt"
 	"strings"
 	"time"
-
-	"github.com/jeremywohl/flatten/v2"
 )
 
 // Log represents single log entry
@@ -18,70 +14,81 @@ type Log struct {
 	Attributes map[string]any `json:"attributes,omitempty"`
 }
 
-func (l *Log) asMap() (map[string]any, error) {
-	dataJSON, err := json.Marshal(l)
-	if err != nil {
-		return nil, err
-	}
-
-	flatStr, err := flatten.FlattenString(string(dataJSON), "", flatten.DotStyle)
-	if err != nil {
-		return nil, err
-	}
-
-	var result map[string]any
-	if err := json.Unmarshal([]byte(flatStr), &result); err != nil {
-		return nil, err
-	}
-
-	return result, nil
-}
-
 // Logs represents logs
 type Logs []Log
 
-func (ls *Logs) AsCSV() (string, error) {
-	attributeKeyMap := map[string]bool{}
+// logCSVHeaders are the columns for the log fields, followed by the attribute columns
+var logCSVHeaders = []string{
+	"timestamp",
+	"trace_id",
+	"span_id",
+	"message",
+}
 
-	logFlatMaps := []map[string]any{}
+// AsCSV converts the logs to CSV with the log fields first, followed by the attributes
+func (ls *Logs) AsCSV() (string, error) {
+	keyMap := map[string]bool{}
 	for _, l := range *ls {
-		flatMap, err := l.asMap()
-		if err != nil {
-			return "", err
-		}
-		logFlatMaps = append(logFlatMaps, flatMap)
-		for k := range flatMap {
-			if strings.HasPrefix(k, "attributes.") {
-				attributeKeyMap[k] = true
-			}
+		for k := range l.Attributes {
+			keyMap[k] = true
 		}
 	}
+	attributeKeys := attributeColumns(keyMap)
 
-	csvData := strings.Builder{}
-
-	headers := []string{
-		"timestamp",
-		"trace_id",
-		"span_id",
-		"message",
+	headers := append([]string{}, logCSVHeaders...)
+	for _, k := range attributeKeys {
+		headers = append(headers, "attributes."+k)
 	}
-	for k := range attributeKeyMap {
-		headers = append(headers, k)
+
+	rows := make([][]string, 0, len(*ls))
+	for _, l := range *ls {
+		row := []string{
+			formatCSVValue(l.Timestamp),
+			l.TraceID,
+			l.SpanID,
+			l.Message,
+		}
+		for _, k := range attributeKeys {
+			row = append(row, formatCSVValue(l.Attributes[k]))
+		}
+		rows = append(rows, row)
 	}
 
-	csvData.WriteString(strings.Join(headers, ",") + "\n")
+	return writeCSV(headers, rows)
+}
 
-	for _, flatMap := range logFlatMaps {
-		row := []string{}
-		for _, header := range headers {
-			if val, ok := flatMap[header]; ok {
-				row = append(row, fmt.Sprintf("%v", val))
-			} else {
-				row = append(row, "")
-			}
+// logLevelKeys are attributes that hold the severity of a log entry
+var logLevelKeys = []string{
+	"level",
+	"severity",
+	"log.level",
+	"levelname",
+	"loglevel",
+	"detected_level",
+}
+
+// IsError reports whether the log entry has an ERROR or higher severity
+func (l *Log) IsError() bool {
+	for _, k := range logLevelKeys {
+		level, ok := l.Attributes[k].(string)
+		if !ok {
+			continue
+		}
+		switch strings.ToUpper(level) {
+		case "ERROR", "CRITICAL", "FATAL", "ALERT", "EMERGENCY":
+			return true
 		}
-		csvData.WriteString(strings.Join(row, ",") + "\n")
 	}
+	return false
+}
 
-	return csvData.String(), nil
+// Errors returns the log entries with an ERROR or higher severity
+func (ls *Logs) Errors() Logs {
+	var errors Logs
+	for _, l := range *ls {
+		if l.IsError() {
+			errors = append(errors, l)
+		}
+	}
+	return errors
 }
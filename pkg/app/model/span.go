@@ -1,45 +1,220 @@
 package model
 
 import (
-	"fmt"
+	"strconv"
 	"strings"
+	"time"
 )
 
-// Span represents single span
-type Span map[string]any
+// SpanKind represents the role of a span in a trace
+type SpanKind string
+
+const (
+	SpanKindUnspecified SpanKind = ""
+	SpanKindInternal    SpanKind = "internal"
+	SpanKindServer      SpanKind = "server"
+	SpanKindClient      SpanKind = "client"
+	SpanKindProducer    SpanKind = "producer"
+	SpanKindConsumer    SpanKind = "consumer"
+)
+
+// ParseSpanKind normalizes a span kind given in a backend-specific form
+// (e.g., "server", "SERVER", "SPAN_KIND_SERVER")
+func ParseSpanKind(s string) SpanKind {
+	switch kind := SpanKind(strings.ToLower(strings.TrimPrefix(strings.ToUpper(s), "SPAN_KIND_"))); kind {
+	case SpanKindInternal, SpanKindServer, SpanKindClient, SpanKindProducer, SpanKindConsumer:
+		return kind
+	}
+	return SpanKindUnspecified
+}
+
+// SpanStatus represents the status of a span
+type SpanStatus string
+
+const (
+	SpanStatusUnset SpanStatus = ""
+	SpanStatusOK    SpanStatus = "ok"
+	SpanStatusError SpanStatus = "error"
+)
+
+// ParseSpanStatus normalizes a status code given in a backend-specific form
+// (e.g., "ERROR", "STATUS_CODE_ERROR", "Ok")
+func ParseSpanStatus(s string) SpanStatus {
+	switch status := SpanStatus(strings.ToLower(strings.TrimPrefix(strings.ToUpper(s), "STATUS_CODE_"))); status {
+	case SpanStatusOK, SpanStatusError:
+		return status
+	}
+	return SpanStatusUnset
+}
+
+// Span represents single span normalized from the backend-specific fields.
+// Attributes holds the remaining fields as returned by the backend.
+type Span struct {
+	TraceID       string         `json:"trace_id"`
+	SpanID        string         `json:"span_id"`
+	ParentSpanID  string         `json:"parent_span_id,omitempty"`
+	ServiceName   string         `json:"service_name,omitempty"`
+	Name          string         `json:"name"`
+	Kind          SpanKind       `json:"kind,omitempty"`
+	StartTime     time.Time      `json:"start_time"`
+	Duration      time.Duration  `json:"duration"`
+	Status        SpanStatus     `json:"status,omitempty"`
+	StatusMessage string         `json:"status_message,omitempty"`
+	Attributes    map[string]any `json:"attributes,omitempty"`
+}
+
+// EndTime returns the time the span ended
+func (s *Span) EndTime() time.Time {
+	return s.StartTime.Add(s.Duration)
+}
 
 // Spans represents spans
 type Spans []Span
 
+// spanCSVHeaders are the columns for the normalized fields, followed by the attribute columns
+var spanCSVHeaders = []string{
+	"trace_id",
+	"span_id",
+	"parent_span_id",
+	"service_name",
+	"name",
+	"kind",
+	"start_time",
+	"duration_ms",
+	"status",
+	"status_message",
+}
+
+// AsCSV converts the spans to CSV with the normalized fields first, followed by the attributes
 func (ss *Spans) AsCSV() (string, error) {
 	keyMap := map[string]bool{}
-
 	for _, s := range *ss {
-		for k := range s {
+		for k := range s.Attributes {
 			keyMap[k] = true
 		}
 	}
+	attributeKeys := attributeColumns(keyMap)
 
-	csvData := strings.Builder{}
+	headers := append([]string{}, spanCSVHeaders...)
+	for _, k := range attributeKeys {
+		headers = append(headers, "attributes."+k)
+	}
 
-	headers := []string{}
-	for k := range keyMap {
-		headers = append(headers, k)
+	rows := make([][]string, 0, len(*ss))
+	for _, span := range *ss {
+		row := []string{
+			span.TraceID,
+			span.SpanID,
+			span.ParentSpanID,
+			span.ServiceName,
+			span.Name,
+			string(span.Kind),
+			formatCSVValue(span.StartTime),
+			formatMillis(span.Duration),
+			string(span.Status),
+			span.StatusMessage,
+		}
+		for _, k := range attributeKeys {
+			row = append(row, formatCSVValue(span.Attributes[k]))
+		}
+		rows = append(rows, row)
 	}
 
-	csvData.WriteString(strings.Join(headers, ",") + "\n")
+	return writeCSV(headers, rows)
+}
 
-	for _, span := range *ss {
-		row := []string{}
-		for _, header := range headers {
-			if val, ok := span[header]; ok {
-				row = append(row, fmt.Sprintf("%v", val))
-			} else {
-				row = append(row, "")
+// spanErrorKeys are attributes whose presence marks a span as failed
+var spanErrorKeys = []string{
+	"error.message",
+	"error.class",
+	"exception.message",
+	"exception.type",
+}
+
+// spanHTTPStatusKeys are attributes that hold the HTTP response status code
+var spanHTTPStatusKeys = []string{
+	"http.statusCode",
+	"http.status_code",
+	"http.response.status_code",
+	"httpResponseCode",
+}
+
+// IsError reports whether the span represents a failed operation, based on
+// the span status, error attributes, exception events or an HTTP 5xx response
+func (s *Span) IsError() bool {
+	if s.Status == SpanStatusError {
+		return true
+	}
+	if v, ok := s.Attributes["error"].(bool); ok && v {
+		return true
+	}
+	for _, k := range spanErrorKeys {
+		if v, ok := s.Attributes[k]; ok && v != nil && v != "" {
+			return true
+		}
+	}
+	for _, k := range spanHTTPStatusKeys {
+		if statusCode(s.Attributes[k]) >= 500 {
+			return true
+		}
+	}
+	return hasExceptionEvent(s.Attributes["events"])
+}
+
+// hasExceptionEvent reports whether the span events, kept as a list of attribute maps,
+// include an OpenTelemetry exception event or an OpenTracing error log
+func hasExceptionEvent(v any) bool {
+	var events []map[string]any
+	switch es := v.(type) {
+	case []map[string]any:
+		events = es
+	case []any:
+		// Events decoded from JSON, e.g., by the file backend
+		for _, e := range es {
+			if event, ok := e.(map[string]any); ok {
+				events = append(events, event)
+			}
+		}
+	}
+
+	for _, event := range events {
+		if event["name"] == "exception" || event["event"] == "error" {
+			return true
+		}
+		for _, k := range spanErrorKeys {
+			if v, ok := event[k]; ok && v != nil && v != "" {
+				return true
 			}
 		}
-		csvData.WriteString(strings.Join(row, ",") + "\n")
 	}
+	return false
+}
+
+// Errors returns the failed spans
+func (ss *Spans) Errors() Spans {
+	var errors Spans
+	for _, s := range *ss {
+		if s.IsError() {
+			errors = append(errors, s)
+		}
+	}
+	return errors
+}
 
-	return csvData.String(), nil
+func statusCode(v any) int {
+	switch c := v.(type) {
+	case float64:
+		return int(c)
+	case int:
+		return c
+	case int64:
+		return int(c)
+	case string:
+		code, err := strconv.Atoi(c)
+		if err != nil {
+			return 0
+		}
+		return code
+	}
+	return 0
 }
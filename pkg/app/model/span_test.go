@@ -0,0 +1,84 @@
+package model
+
+import "testing"
+
+func TestSpanIsError(t *testing.T) {
+	tests := []struct {
+		name string
+		span Span
+		want bool
+	}{
+		{
+			name: "successful span",
+			span: Span{Status: SpanStatusOK, Attributes: map[string]any{"http.status_code": float64(200)}},
+			want: false,
+		},
+		{
+			name: "error status",
+			span: Span{Status: SpanStatusError},
+			want: true,
+		},
+		{
+			name: "error attribute",
+			span: Span{Attributes: map[string]any{"error": true}},
+			want: true,
+		},
+		{
+			name: "empty error message",
+			span: Span{Attributes: map[string]any{"error.message": ""}},
+			want: false,
+		},
+		{
+			name: "exception attribute",
+			span: Span{Attributes: map[string]any{"exception.type": "java.io.IOException"}},
+			want: true,
+		},
+		{
+			name: "HTTP 5xx as string",
+			span: Span{Attributes: map[string]any{"http.response.status_code": "503"}},
+			want: true,
+		},
+		{
+			name: "HTTP 4xx",
+			span: Span{Attributes: map[string]any{"http.statusCode": float64(404)}},
+			want: false,
+		},
+		{
+			name: "OpenTelemetry exception event",
+			span: Span{Attributes: map[string]any{"events": []map[string]any{
+				{"name": "exception", "exception.message": "timeout"},
+			}}},
+			want: true,
+		},
+		{
+			name: "OpenTracing error log",
+			span: Span{Attributes: map[string]any{"events": []map[string]any{
+				{"event": "error", "message": "timeout"},
+			}}},
+			want: true,
+		},
+		{
+			name: "exception event decoded from JSON",
+			span: Span{Attributes: map[string]any{"events": []any{
+				map[string]any{"name": "retry"},
+				map[string]any{"name": "log", "exception.type": "TimeoutError"},
+			}}},
+			want: true,
+		},
+		{
+			name: "non-exception events",
+			span: Span{Attributes: map[string]any{"events": []map[string]any{
+				{"name": "cache miss"},
+			}}},
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.span.IsError(); got != tt.want {
+				t.Errorf("IsError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,273 @@
+package model
+
+import (
+	"reflect"
+	"slices"
+	"sort"
+	"time"
+)
+
+// SpanNode represents a span and its children in the span tree
+type SpanNode struct {
+	Span     *Span
+	Children []*SpanNode
+	// SelfTime is the time spent in the span itself, excluding the time covered by its children
+	SelfTime time.Duration
+}
+
+// SpanTree represents the parent/child structure of the spans of a trace
+type SpanTree struct {
+	// Roots are the spans without a parent span ID. A complete trace has exactly one root.
+	Roots []*SpanNode
+	// Orphans are the spans whose parent span is missing from the trace, including the spans
+	// counted in DuplicateIDs and CycleBreaks
+	Orphans []*SpanNode
+	// DuplicateIDs is the number of spans attached as orphans as another span has the same span ID
+	DuplicateIDs int
+	// CycleBreaks is the number of spans attached as orphans to break a cycle of parent span IDs
+	CycleBreaks int
+}
+
+// CriticalPathSegment represents a part of the critical path spent in a span itself
+type CriticalPathSegment struct {
+	Span  *Span
+	Start time.Time
+	End   time.Time
+}
+
+// Duration returns the length of the segment
+func (s CriticalPathSegment) Duration() time.Duration {
+	return s.End.Sub(s.Start)
+}
+
+// Tree builds the span tree from the parent span IDs and computes the self time of each span.
+// Children are sorted by start time. Every span is in the tree: spans reported more than once are
+// kept once, and spans that cannot be placed under their parent (another span with the same ID,
+// or a cycle of parent span IDs) are attached as orphans.
+func (ss *Spans) Tree() *SpanTree {
+	nodes := make(map[string]*SpanNode, len(*ss))
+	ordered := make([]*SpanNode, 0, len(*ss))
+	tree := &SpanTree{}
+	duplicates := map[*SpanNode]bool{}
+	for i := range *ss {
+		span := &(*ss)[i]
+		node := &SpanNode{Span: span}
+		if span.SpanID != "" {
+			if first, ok := nodes[span.SpanID]; ok {
+				if reflect.DeepEqual(*first.Span, *span) {
+					// The same span reported more than once
+					continue
+				}
+				duplicates[node] = true
+			} else {
+				nodes[span.SpanID] = node
+			}
+		}
+		ordered = append(ordered, node)
+	}
+
+	parents := map[*SpanNode]*SpanNode{}
+	for _, node := range ordered {
+		parentID := node.Span.ParentSpanID
+		switch parent, ok := nodes[parentID]; {
+		case duplicates[node]:
+			tree.Orphans = append(tree.Orphans, node)
+			tree.DuplicateIDs++
+		case parentID == "" || parentID == node.Span.SpanID:
+			tree.Roots = append(tree.Roots, node)
+		case ok:
+			parent.Children = append(parent.Children, node)
+			parents[node] = parent
+		default:
+			tree.Orphans = append(tree.Orphans, node)
+		}
+	}
+
+	// Spans in a cycle of parent span IDs are not reachable from the roots and orphans,
+	// so the cycles are broken by detaching the first unreachable span from its parent
+	reachable := make(map[*SpanNode]bool, len(ordered))
+	for _, n := range tree.Nodes() {
+		reachable[n] = true
+	}
+	for _, node := range ordered {
+		if reachable[node] {
+			continue
+		}
+		parent := parents[node]
+		parent.Children = slices.DeleteFunc(parent.Children, func(c *SpanNode) bool { return c == node })
+		tree.Orphans = append(tree.Orphans, node)
+		tree.CycleBreaks++
+		walkNodes(node, func(n *SpanNode) { reachable[n] = true })
+	}
+
+	for _, node := range ordered {
+		sortNodesByStartTime(node.Children)
+		node.SelfTime = selfTime(node)
+	}
+	sortNodesByStartTime(tree.Roots)
+	sortNodesByStartTime(tree.Orphans)
+
+	return tree
+}
+
+// MissingParents returns the number of orphans whose parent span is missing from the trace
+func (t *SpanTree) MissingParents() int {
+	return len(t.Orphans) - t.DuplicateIDs - t.CycleBreaks
+}
+
+// HasMultipleRoots reports whether the trace has more than one root span,
+// which usually means that the context propagation is broken somewhere
+func (t *SpanTree) HasMultipleRoots() bool {
+	return len(t.Roots) > 1
+}
+
+// Nodes returns all the nodes reachable from the roots and orphans in depth-first order
+func (t *SpanTree) Nodes() []*SpanNode {
+	var nodes []*SpanNode
+	appendNode := func(n *SpanNode) { nodes = append(nodes, n) }
+	for _, n := range t.Roots {
+		walkNodes(n, appendNode)
+	}
+	for _, n := range t.Orphans {
+		walkNodes(n, appendNode)
+	}
+	return nodes
+}
+
+// walkNodes calls fn for the node and its descendants in depth-first order
+func walkNodes(n *SpanNode, fn func(*SpanNode)) {
+	fn(n)
+	for _, c := range n.Children {
+		walkNodes(c, fn)
+	}
+}
+
+// TopSelfTime returns up to n spans with the longest self time
+func (t *SpanTree) TopSelfTime(n int) []*SpanNode {
+	nodes := t.Nodes()
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return nodes[i].SelfTime > nodes[j].SelfTime
+	})
+	if len(nodes) > n {
+		nodes = nodes[:n]
+	}
+	return nodes
+}
+
+// CriticalPath returns the segments of the critical path in chronological order, i.e., the chain of
+// work that determines the end-to-end latency of the trace. It starts from the longest root span,
+// or from the longest orphan when no root span was found. Consecutive segments of the same span are merged.
+func (t *SpanTree) CriticalPath() []CriticalPathSegment {
+	candidates := t.Roots
+	if len(candidates) == 0 {
+		candidates = t.Orphans
+	}
+
+	var root *SpanNode
+	for _, n := range candidates {
+		if root == nil || n.Span.Duration > root.Span.Duration {
+			root = n
+		}
+	}
+	if root == nil {
+		return nil
+	}
+
+	segments := criticalPath(root, root.Span.EndTime())
+
+	// Segments are collected backwards from the end of the trace
+	var path []CriticalPathSegment
+	for i := len(segments) - 1; i >= 0; i-- {
+		seg := segments[i]
+		if seg.Duration() <= 0 {
+			continue
+		}
+		if last := len(path) - 1; last >= 0 && path[last].Span == seg.Span && path[last].End.Equal(seg.Start) {
+			path[last].End = seg.End
+			continue
+		}
+		path = append(path, seg)
+	}
+	return path
+}
+
+// criticalPath walks backwards from the end of the span: the child that finished last before the
+// cursor is on the critical path, and the gaps between such children are spent in the span itself.
+// The segments are returned in reverse chronological order.
+func criticalPath(n *SpanNode, until time.Time) []CriticalPathSegment {
+	start := n.Span.StartTime
+	cursor := minTime(n.Span.EndTime(), until)
+
+	var segments []CriticalPathSegment
+	for {
+		var next *SpanNode
+		for _, c := range n.Children {
+			if !c.Span.StartTime.Before(cursor) {
+				continue
+			}
+			if next == nil || c.Span.EndTime().After(next.Span.EndTime()) {
+				next = c
+			}
+		}
+		if next == nil || !cursor.After(start) {
+			break
+		}
+
+		childEnd := minTime(next.Span.EndTime(), cursor)
+		if childEnd.Before(cursor) {
+			segments = append(segments, CriticalPathSegment{Span: n.Span, Start: childEnd, End: cursor})
+		}
+		segments = append(segments, criticalPath(next, childEnd)...)
+
+		// Children that started before the parent (e.g., due to clock skew) are clipped
+		cursor = maxTime(next.Span.StartTime, start)
+	}
+
+	if cursor.After(start) {
+		segments = append(segments, CriticalPathSegment{Span: n.Span, Start: start, End: cursor})
+	}
+	return segments
+}
+
+// selfTime returns the duration of the span not covered by any of its children,
+// clipping the children to the span and merging overlapping (e.g., concurrent) children
+func selfTime(n *SpanNode) time.Duration {
+	start, end := n.Span.StartTime, n.Span.EndTime()
+
+	var covered time.Duration
+	var coveredEnd time.Time
+	// Children are sorted by start time
+	for _, c := range n.Children {
+		cs := maxTime(maxTime(c.Span.StartTime, start), coveredEnd)
+		ce := minTime(c.Span.EndTime(), end)
+		if ce.After(cs) {
+			covered += ce.Sub(cs)
+			coveredEnd = ce
+		}
+	}
+
+	if self := n.Span.Duration - covered; self > 0 {
+		return self
+	}
+	return 0
+}
+
+func sortNodesByStartTime(nodes []*SpanNode) {
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return nodes[i].Span.StartTime.Before(nodes[j].Span.StartTime)
+	})
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,304 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+// testTraceStart is the start time of the spans built by testSpan
+var testTraceStart = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// testSpan builds a span running from start to end milliseconds after testTraceStart
+func testSpan(id, parentID string, start, end int) Span {
+	return Span{
+		SpanID:       id,
+		ParentSpanID: parentID,
+		Name:         id,
+		StartTime:    testTraceStart.Add(time.Duration(start) * time.Millisecond),
+		Duration:     time.Duration(end-start) * time.Millisecond,
+	}
+}
+
+func TestSpansTree(t *testing.T) {
+	spans := Spans{
+		testSpan("b", "root", 40, 70),
+		testSpan("root", "", 0, 100),
+		testSpan("a", "root", 10, 30),
+		testSpan("a", "root", 10, 30),
+		testSpan("orphan", "missing", 20, 50),
+		testSpan("self-parent", "self-parent", 0, 10),
+	}
+
+	tree := spans.Tree()
+
+	if got := nodeNames(tree.Roots); got != "root,self-parent" {
+		t.Errorf("Roots = %s, want root,self-parent", got)
+	}
+	if got := nodeNames(tree.Orphans); got != "orphan" {
+		t.Errorf("Orphans = %s, want orphan", got)
+	}
+	if !tree.HasMultipleRoots() {
+		t.Error("HasMultipleRoots() = false, want true")
+	}
+	if got := len(tree.Nodes()); got != 5 {
+		t.Errorf("Nodes() returned %d nodes, want 5 as duplicated spans are kept once", got)
+	}
+
+	var root *SpanNode
+	for _, n := range tree.Roots {
+		if n.Span.SpanID == "root" {
+			root = n
+		}
+	}
+	if got := nodeNames(root.Children); got != "a,b" {
+		t.Errorf("children = %s, want a,b sorted by start time", got)
+	}
+}
+
+func TestSpansTreeDetachedSpans(t *testing.T) {
+	// unnamed builds a span without an ID, named to tell it apart in the results
+	unnamed := func(name, parentID string, start, end int) Span {
+		s := testSpan("", parentID, start, end)
+		s.Name = name
+		return s
+	}
+	// shared builds a span reusing the ID of another span
+	shared := func(name, id, parentID string, start, end int) Span {
+		s := testSpan(id, parentID, start, end)
+		s.Name = name
+		return s
+	}
+
+	tests := []struct {
+		name             string
+		spans            Spans
+		wantRoots        string
+		wantOrphans      string
+		wantDuplicateIDs int
+		wantCycleBreaks  int
+	}{
+		{
+			name: "spans without an ID",
+			spans: Spans{
+				testSpan("root", "", 0, 100),
+				unnamed("x", "root", 10, 20),
+				unnamed("y", "root", 30, 40),
+				unnamed("z", "", 50, 60),
+			},
+			wantRoots: "root,z",
+		},
+		{
+			name: "different spans with the same ID",
+			spans: Spans{
+				testSpan("root", "", 0, 100),
+				shared("client", "a", "root", 10, 50),
+				shared("server", "a", "root", 20, 40),
+			},
+			wantRoots:        "root",
+			wantOrphans:      "server",
+			wantDuplicateIDs: 1,
+		},
+		{
+			name: "cycle of parent span IDs",
+			spans: Spans{
+				testSpan("root", "", 0, 100),
+				testSpan("a", "b", 10, 50),
+				testSpan("b", "a", 20, 40),
+			},
+			wantRoots:       "root",
+			wantOrphans:     "a",
+			wantCycleBreaks: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree := tt.spans.Tree()
+
+			if got := nodeNames(tree.Roots); got != tt.wantRoots {
+				t.Errorf("Roots = %s, want %s", got, tt.wantRoots)
+			}
+			if got := nodeNames(tree.Orphans); got != tt.wantOrphans {
+				t.Errorf("Orphans = %s, want %s", got, tt.wantOrphans)
+			}
+			if tree.DuplicateIDs != tt.wantDuplicateIDs {
+				t.Errorf("DuplicateIDs = %d, want %d", tree.DuplicateIDs, tt.wantDuplicateIDs)
+			}
+			if tree.CycleBreaks != tt.wantCycleBreaks {
+				t.Errorf("CycleBreaks = %d, want %d", tree.CycleBreaks, tt.wantCycleBreaks)
+			}
+			if got := tree.MissingParents(); got != 0 {
+				t.Errorf("MissingParents() = %d, want 0", got)
+			}
+			if got := len(tree.Nodes()); got != len(tt.spans) {
+				t.Errorf("Nodes() returned %d nodes, want every one of the %d spans", got, len(tt.spans))
+			}
+		})
+	}
+}
+
+func TestSelfTime(t *testing.T) {
+	tests := []struct {
+		name     string
+		children Spans
+		want     time.Duration
+	}{
+		{
+			name: "no children",
+			want: 100 * time.Millisecond,
+		},
+		{
+			name:     "sequential children",
+			children: Spans{testSpan("a", "root", 10, 30), testSpan("b", "root", 40, 70)},
+			want:     50 * time.Millisecond,
+		},
+		{
+			name:     "overlapping children",
+			children: Spans{testSpan("a", "root", 10, 50), testSpan("b", "root", 30, 70)},
+			want:     40 * time.Millisecond,
+		},
+		{
+			name:     "child within another child",
+			children: Spans{testSpan("a", "root", 10, 80), testSpan("b", "root", 20, 30)},
+			want:     30 * time.Millisecond,
+		},
+		{
+			name:     "child ending after the parent",
+			children: Spans{testSpan("a", "root", 90, 150)},
+			want:     90 * time.Millisecond,
+		},
+		{
+			name:     "child starting before the parent",
+			children: Spans{testSpan("a", "root", -10, 20)},
+			want:     80 * time.Millisecond,
+		},
+		{
+			name:     "children covering the whole parent",
+			children: Spans{testSpan("a", "root", 0, 60), testSpan("b", "root", 50, 100)},
+			want:     0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spans := append(Spans{testSpan("root", "", 0, 100)}, tt.children...)
+			tree := spans.Tree()
+			if got := tree.Roots[0].SelfTime; got != tt.want {
+				t.Errorf("SelfTime = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCriticalPath(t *testing.T) {
+	// segment describes a critical path segment as the span name and its start and end in milliseconds
+	type segment struct {
+		span       string
+		start, end int
+	}
+
+	tests := []struct {
+		name  string
+		spans Spans
+		want  []segment
+	}{
+		{
+			name:  "no spans",
+			spans: Spans{},
+		},
+		{
+			name:  "single span",
+			spans: Spans{testSpan("root", "", 0, 100)},
+			want:  []segment{{"root", 0, 100}},
+		},
+		{
+			name: "sequential children",
+			spans: Spans{
+				testSpan("root", "", 0, 100),
+				testSpan("a", "root", 10, 30),
+				testSpan("b", "root", 40, 70),
+			},
+			want: []segment{{"root", 0, 10}, {"a", 10, 30}, {"root", 30, 40}, {"b", 40, 70}, {"root", 70, 100}},
+		},
+		{
+			name: "concurrent children",
+			spans: Spans{
+				testSpan("root", "", 0, 100),
+				testSpan("fast", "root", 10, 50),
+				testSpan("slow", "root", 10, 90),
+			},
+			want: []segment{{"root", 0, 10}, {"slow", 10, 90}, {"root", 90, 100}},
+		},
+		{
+			name: "nested children",
+			spans: Spans{
+				testSpan("root", "", 0, 100),
+				testSpan("a", "root", 0, 100),
+				testSpan("b", "a", 20, 60),
+			},
+			want: []segment{{"a", 0, 20}, {"b", 20, 60}, {"a", 60, 100}},
+		},
+		{
+			name: "child ending after the parent is clipped",
+			spans: Spans{
+				testSpan("root", "", 0, 100),
+				testSpan("a", "root", 50, 150),
+			},
+			want: []segment{{"root", 0, 50}, {"a", 50, 100}},
+		},
+		{
+			name: "longest orphan without roots",
+			spans: Spans{
+				testSpan("short", "missing", 0, 10),
+				testSpan("long", "missing", 0, 50),
+			},
+			want: []segment{{"long", 0, 50}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := tt.spans.Tree().CriticalPath()
+
+			var got []segment
+			for _, s := range path {
+				got = append(got, segment{
+					span:  s.Span.Name,
+					start: int(s.Start.Sub(testTraceStart).Milliseconds()),
+					end:   int(s.End.Sub(testTraceStart).Milliseconds()),
+				})
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("CriticalPath() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("CriticalPath() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestTopSelfTime(t *testing.T) {
+	spans := Spans{
+		testSpan("root", "", 0, 100),
+		testSpan("a", "root", 10, 30),
+		testSpan("b", "root", 40, 90),
+	}
+
+	if got := nodeNames(spans.Tree().TopSelfTime(2)); got != "b,root" {
+		t.Errorf("TopSelfTime() = %s, want b,root", got)
+	}
+}
+
+func nodeNames(nodes []*SpanNode) string {
+	var names string
+	for i, n := range nodes {
+		if i > 0 {
+			names += ","
+		}
+		names += n.Span.Name
+	}
+	return names
+}
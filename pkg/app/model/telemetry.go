@@ -7,29 +7,59 @@ import (
 
 // Telemetry represents telemetry data including spans and logs
 type Telemetry struct {
-	Spans Spans `json:"spans"`
-	Logs  Logs  `json:"logs"`
+	Spans    Spans     `json:"spans"`
+	Logs     Logs      `json:"logs"`
+	Warnings []Warning `json:"warnings,omitempty"`
 }
 
+// Warning represents a non-fatal problem that occurred while collecting telemetry,
+// such as a failed log fetch in partial-result mode
+type Warning struct {
+	Source  string `json:"source"` // e.g., "spans", "logs"
+	Message string `json:"message"`
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("[%s] %s", w.Source, w.Message)
+}
+
+// TimeRange returns the earliest span start and the latest span end
 func (t *Telemetry) TimeRange() (time.Time, time.Time) {
 	var earliest, latest time.Time
 
-	// Check spans for timestamps
+	// Check spans for start and end times
 	for _, span := range t.Spans {
-		if ts, ok := span["timestamp"].(float64); ok {
-			t := time.Unix(int64(ts/1000), 0)
-			if earliest.IsZero() || t.Before(earliest) {
-				earliest = t
-			}
-			if latest.IsZero() || t.After(latest) {
-				latest = t
-			}
+		if span.StartTime.IsZero() {
+			continue
+		}
+		if earliest.IsZero() || span.StartTime.Before(earliest) {
+			earliest = span.StartTime
+		}
+		if end := span.EndTime(); latest.IsZero() || end.After(latest) {
+			latest = end
 		}
 	}
 
 	return earliest, latest
 }
 
+// WithoutErrors returns the telemetry without the failing spans and the error logs,
+// keeping the warnings
+func (t *Telemetry) WithoutErrors() *Telemetry {
+	rest := &Telemetry{Warnings: t.Warnings}
+	for _, s := range t.Spans {
+		if !s.IsError() {
+			rest.Spans = append(rest.Spans, s)
+		}
+	}
+	for _, l := range t.Logs {
+		if !l.IsError() {
+			rest.Logs = append(rest.Logs, l)
+		}
+	}
+	return rest
+}
+
 // AsCSV converts telemetry data to CSV format
 func (t *Telemetry) AsCSV() (spans string, logs string, err error) {
 	spans, err = t.Spans.AsCSV()
@@ -52,5 +82,5 @@ func (t *Telemetry) RoughTokenEstimate() (int, error) {
 		return 0, fmt.Errorf("failed to convert telemetry to CSV for token estimation: %w", err)
 	}
 
-	return len([]rune(string(spans+logs))) / 3, nil
+	return EstimateTokens(spans + logs), nil
 }
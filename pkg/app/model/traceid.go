@@ -0,0 +1,163 @@
+package model
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// TraceIDFormat represents a textual representation of trace IDs used by a backend
+type TraceIDFormat string
+
+const (
+	// TraceIDFormatW3C is the 32-hex W3C Trace Context form used by OpenTelemetry
+	TraceIDFormatW3C TraceIDFormat = "w3c"
+	// TraceIDFormatHex is the 16-hex form for 64-bit IDs and the 32-hex form otherwise (e.g., Jaeger)
+	TraceIDFormatHex TraceIDFormat = "hex"
+	// TraceIDFormatXRay is the AWS X-Ray form (e.g., 1-5759e988-bd862e3fe1be46a994272793)
+	TraceIDFormatXRay TraceIDFormat = "xray"
+	// TraceIDFormatDatadog is the decimal form of the lower 64 bits used by Datadog
+	TraceIDFormatDatadog TraceIDFormat = "datadog"
+)
+
+var (
+	hexTraceIDPattern  = regexp.MustCompile(`^(?:[0-9a-fA-F]{32}|[0-9a-fA-F]{16})$`)
+	xrayTraceIDPattern = regexp.MustCompile(`^1-([0-9a-fA-F]{8})-([0-9a-fA-F]{24})$`)
+	gcpTraceIDPattern  = regexp.MustCompile(`^projects/[^/]+/traces/([0-9a-fA-F]{32})$`)
+	decimalPattern     = regexp.MustCompile(`^[0-9]{1,20}$`)
+)
+
+// TraceID is a trace ID normalized from any of the supported formats.
+// It also keeps the form it was given in, so compare trace IDs by one of their normalized forms.
+type TraceID struct {
+	high uint64
+	low  uint64
+	raw  string
+}
+
+// ParseTraceID parses a trace ID given in any of the following forms:
+//   - 32-hex W3C trace ID (e.g., 4bf92f3577b34da6a3ce929d0e0e4736)
+//   - 16-hex 64-bit trace ID (e.g., a3ce929d0e0e4736)
+//   - AWS X-Ray trace ID (e.g., 1-5759e988-bd862e3fe1be46a994272793)
+//   - GCP trace resource name (e.g., projects/my-project/traces/4bf92f3577b34da6a3ce929d0e0e4736)
+//   - Datadog decimal 64-bit trace ID (e.g., 11803532876627986230)
+//
+// A 16-digit decimal string is ambiguous and is parsed as hex.
+func ParseTraceID(s string) (TraceID, error) {
+	s = strings.TrimSpace(s)
+
+	id, err := parseTraceID(s)
+	if err != nil {
+		return TraceID{}, err
+	}
+	id.raw = s
+	return id, nil
+}
+
+func parseTraceID(s string) (TraceID, error) {
+	switch {
+	case hexTraceIDPattern.MatchString(s):
+		return parseHexTraceID(s)
+	case xrayTraceIDPattern.MatchString(s):
+		m := xrayTraceIDPattern.FindStringSubmatch(s)
+		return parseHexTraceID(m[1] + m[2])
+	case gcpTraceIDPattern.MatchString(s):
+		return parseHexTraceID(gcpTraceIDPattern.FindStringSubmatch(s)[1])
+	case decimalPattern.MatchString(s):
+		low, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return TraceID{}, fmt.Errorf("invalid trace ID %q: %w", s, err)
+		}
+		if low == 0 {
+			return TraceID{}, errors.New("invalid trace ID: all zeros")
+		}
+		return TraceID{low: low}, nil
+	}
+
+	return TraceID{}, fmt.Errorf("invalid trace ID %q: unsupported format", s)
+}
+
+func parseHexTraceID(s string) (TraceID, error) {
+	b, err := hex.DecodeString(fmt.Sprintf("%032s", s))
+	if err != nil {
+		return TraceID{}, fmt.Errorf("invalid trace ID %q: %w", s, err)
+	}
+	id := TraceID{
+		high: binary.BigEndian.Uint64(b[:8]),
+		low:  binary.BigEndian.Uint64(b[8:]),
+	}
+	if id.IsZero() {
+		return TraceID{}, errors.New("invalid trace ID: all zeros")
+	}
+	return id, nil
+}
+
+// IsZero reports whether the trace ID is unset
+func (t TraceID) IsZero() bool {
+	return t.high == 0 && t.low == 0
+}
+
+// Is64Bit reports whether the trace ID fits in 64 bits
+func (t TraceID) Is64Bit() bool {
+	return t.high == 0
+}
+
+// W3C returns the 32-hex W3C form
+func (t TraceID) W3C() string {
+	return fmt.Sprintf("%016x%016x", t.high, t.low)
+}
+
+// Hex returns the 16-hex form for 64-bit IDs and the 32-hex form otherwise
+func (t TraceID) Hex() string {
+	if t.Is64Bit() {
+		return fmt.Sprintf("%016x", t.low)
+	}
+	return t.W3C()
+}
+
+// XRay returns the AWS X-Ray form
+func (t TraceID) XRay() string {
+	w3c := t.W3C()
+	return fmt.Sprintf("1-%s-%s", w3c[:8], w3c[8:])
+}
+
+// Datadog returns the decimal form of the lower 64 bits
+func (t TraceID) Datadog() string {
+	return strconv.FormatUint(t.low, 10)
+}
+
+// GCP returns the GCP trace resource name in the project
+func (t TraceID) GCP(projectID string) string {
+	return fmt.Sprintf("projects/%s/traces/%s", projectID, t.W3C())
+}
+
+// Format returns the trace ID in the given format, defaulting to the W3C form
+func (t TraceID) Format(format TraceIDFormat) string {
+	switch format {
+	case TraceIDFormatHex:
+		return t.Hex()
+	case TraceIDFormatXRay:
+		return t.XRay()
+	case TraceIDFormatDatadog:
+		return t.Datadog()
+	default:
+		return t.W3C()
+	}
+}
+
+// Raw returns the trace ID in the form it was given to ParseTraceID, defaulting to the W3C form
+func (t TraceID) Raw() string {
+	if t.raw == "" {
+		return t.W3C()
+	}
+	return t.raw
+}
+
+// String returns the hex form
+func (t TraceID) String() string {
+	return t.Hex()
+}
@@ -0,0 +1,140 @@
+package model
+
+import "testing"
+
+func TestParseTraceID(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		wantW3C     string
+		wantHex     string
+		wantXRay    string
+		wantDatadog string
+		wantRaw     string
+		wantErr     bool
+	}{
+		{
+			name:        "W3C",
+			input:       "4bf92f3577b34da6a3ce929d0e0e4736",
+			wantW3C:     "4bf92f3577b34da6a3ce929d0e0e4736",
+			wantHex:     "4bf92f3577b34da6a3ce929d0e0e4736",
+			wantXRay:    "1-4bf92f35-77b34da6a3ce929d0e0e4736",
+			wantDatadog: "11803532876627986230",
+			wantRaw:     "4bf92f3577b34da6a3ce929d0e0e4736",
+		},
+		{
+			name:        "uppercase W3C with spaces",
+			input:       " 4BF92F3577B34DA6A3CE929D0E0E4736\n",
+			wantW3C:     "4bf92f3577b34da6a3ce929d0e0e4736",
+			wantHex:     "4bf92f3577b34da6a3ce929d0e0e4736",
+			wantXRay:    "1-4bf92f35-77b34da6a3ce929d0e0e4736",
+			wantDatadog: "11803532876627986230",
+			wantRaw:     "4BF92F3577B34DA6A3CE929D0E0E4736",
+		},
+		{
+			name:        "64-bit hex",
+			input:       "a3ce929d0e0e4736",
+			wantW3C:     "0000000000000000a3ce929d0e0e4736",
+			wantHex:     "a3ce929d0e0e4736",
+			wantXRay:    "1-00000000-00000000a3ce929d0e0e4736",
+			wantDatadog: "11803532876627986230",
+			wantRaw:     "a3ce929d0e0e4736",
+		},
+		{
+			name:        "X-Ray",
+			input:       "1-5759e988-bd862e3fe1be46a994272793",
+			wantW3C:     "5759e988bd862e3fe1be46a994272793",
+			wantHex:     "5759e988bd862e3fe1be46a994272793",
+			wantXRay:    "1-5759e988-bd862e3fe1be46a994272793",
+			wantDatadog: "16266516598257821587",
+			wantRaw:     "1-5759e988-bd862e3fe1be46a994272793",
+		},
+		{
+			name:        "GCP resource name",
+			input:       "projects/my-project/traces/4bf92f3577b34da6a3ce929d0e0e4736",
+			wantW3C:     "4bf92f3577b34da6a3ce929d0e0e4736",
+			wantHex:     "4bf92f3577b34da6a3ce929d0e0e4736",
+			wantXRay:    "1-4bf92f35-77b34da6a3ce929d0e0e4736",
+			wantDatadog: "11803532876627986230",
+			wantRaw:     "projects/my-project/traces/4bf92f3577b34da6a3ce929d0e0e4736",
+		},
+		{
+			name:        "Datadog decimal",
+			input:       "11803532876627986230",
+			wantW3C:     "0000000000000000a3ce929d0e0e4736",
+			wantHex:     "a3ce929d0e0e4736",
+			wantXRay:    "1-00000000-00000000a3ce929d0e0e4736",
+			wantDatadog: "11803532876627986230",
+			wantRaw:     "11803532876627986230",
+		},
+		{
+			name:        "16-digit decimal is parsed as hex",
+			input:       "1234567890123456",
+			wantW3C:     "00000000000000001234567890123456",
+			wantHex:     "1234567890123456",
+			wantXRay:    "1-00000000-000000001234567890123456",
+			wantDatadog: "1311768467284833366",
+			wantRaw:     "1234567890123456",
+		},
+		{name: "all zeros", input: "00000000000000000000000000000000", wantErr: true},
+		{name: "decimal zero", input: "0", wantErr: true},
+		{name: "decimal overflow", input: "99999999999999999999", wantErr: true},
+		{name: "wrong length", input: "4bf92f3577b34da6a3", wantErr: true},
+		{name: "not hex", input: "4bf92f3577b34da6a3ce929d0e0e473g", wantErr: true},
+		{name: "query injection", input: "4bf92f3577b34da6a3ce929d0e0e4736' OR 1=1", wantErr: true},
+		{name: "empty", input: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTraceID(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTraceID() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if w3c := got.W3C(); w3c != tt.wantW3C {
+				t.Errorf("W3C() = %s, want %s", w3c, tt.wantW3C)
+			}
+			if hex := got.Hex(); hex != tt.wantHex {
+				t.Errorf("Hex() = %s, want %s", hex, tt.wantHex)
+			}
+			if xray := got.XRay(); xray != tt.wantXRay {
+				t.Errorf("XRay() = %s, want %s", xray, tt.wantXRay)
+			}
+			if datadog := got.Datadog(); datadog != tt.wantDatadog {
+				t.Errorf("Datadog() = %s, want %s", datadog, tt.wantDatadog)
+			}
+			if raw := got.Raw(); raw != tt.wantRaw {
+				t.Errorf("Raw() = %s, want %s", raw, tt.wantRaw)
+			}
+		})
+	}
+}
+
+func TestTraceIDFormat(t *testing.T) {
+	id, err := ParseTraceID("1-5759e988-bd862e3fe1be46a994272793")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		format TraceIDFormat
+		want   string
+	}{
+		{format: "", want: "5759e988bd862e3fe1be46a994272793"},
+		{format: TraceIDFormatW3C, want: "5759e988bd862e3fe1be46a994272793"},
+		{format: TraceIDFormatHex, want: "5759e988bd862e3fe1be46a994272793"},
+		{format: TraceIDFormatXRay, want: "1-5759e988-bd862e3fe1be46a994272793"},
+		{format: TraceIDFormatDatadog, want: "16266516598257821587"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			if got := id.Format(tt.format); got != tt.want {
+				t.Errorf("Format() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,277 @@
+package app
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ymtdzzz/telemetry-glue/pkg/app/model"
+	"github.com/ymtdzzz/telemetry-glue/pkg/glue/backend"
+)
+
+// TraceRef is a trace reference given by users, either a raw trace ID or a URL of an observability UI
+type TraceRef struct {
+	TraceID   string
+	AccountID int                // New Relic account ID, 0 if unknown
+	ProjectID string             // GCP project ID, empty if unknown
+	TimeRange *backend.TimeRange // nil if the URL has no absolute time window
+}
+
+// traceIDSegmentPattern matches a path segment or value that looks like a hex trace ID
+var traceIDSegmentPattern = regexp.MustCompile(`^(?:[0-9a-fA-F]{32}|[0-9a-fA-F]{16})$`)
+
+// ParseTraceRef parses a raw trace ID or one of the following URLs:
+//   - New Relic distributed tracing (https://one.newrelic.com/...)
+//   - GCP Trace explorer (https://console.cloud.google.com/traces/...)
+//   - Grafana Explore (https://<grafana>/explore?...)
+//   - Jaeger UI (https://<jaeger>/trace/<trace-id>)
+func ParseTraceRef(s string) (*TraceRef, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "http://") && !strings.HasPrefix(s, "https://") {
+		return &TraceRef{TraceID: s}, nil
+	}
+
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse trace URL: %w", err)
+	}
+
+	var ref *TraceRef
+	switch {
+	case u.Hostname() == "newrelic.com" || strings.HasSuffix(u.Hostname(), ".newrelic.com"):
+		ref = parseNewRelicURL(u)
+	case u.Hostname() == "console.cloud.google.com":
+		ref = parseGCPTraceURL(u)
+	case strings.HasSuffix(strings.TrimRight(u.Path, "/"), "/explore"):
+		ref = parseGrafanaURL(u)
+	case strings.Contains(u.Path, "/trace/"):
+		ref = parseJaegerURL(u)
+	default:
+		return nil, fmt.Errorf("unsupported trace URL: %s", s)
+	}
+
+	if ref.TraceID == "" {
+		return nil, fmt.Errorf("no trace ID found in the URL: %s", s)
+	}
+	return ref, nil
+}
+
+// parseNewRelicURL extracts the trace from a New Relic URL. The UI keeps its state in
+// query parameters, some of which (pane, overlay, launcher, state) are base64-encoded JSON.
+func parseNewRelicURL(u *url.URL) *TraceRef {
+	q := u.Query()
+	ref := &TraceRef{}
+
+	for _, key := range []string{"account", "platform[accountId]"} {
+		if id, err := strconv.Atoi(q.Get(key)); err == nil {
+			ref.AccountID = id
+			break
+		}
+	}
+
+	values := map[string]any{}
+	for key, vs := range q {
+		values[key] = vs[0]
+	}
+	for _, key := range []string{"pane", "overlay", "launcher", "state"} {
+		if state := decodeNewRelicState(q.Get(key)); state != nil {
+			collectValues(state, values)
+		}
+	}
+
+	if id, ok := values["traceId"].(string); ok {
+		ref.TraceID = id
+	} else {
+		ref.TraceID = lastTraceIDSegment(u.Path)
+	}
+	if ref.AccountID == 0 {
+		if id, ok := values["accountId"].(float64); ok {
+			ref.AccountID = int(id)
+		}
+	}
+
+	begin := epochMilli(firstOf(values, "platform[timeRange][begin_time]", "begin_time", "begin"))
+	end := epochMilli(firstOf(values, "platform[timeRange][end_time]", "end_time", "end"))
+	if end.IsZero() && !begin.IsZero() {
+		if ms, ok := milliValue(firstOf(values, "platform[timeRange][duration]", "duration")); ok {
+			end = begin.Add(time.Duration(ms) * time.Millisecond)
+		}
+	}
+	if !begin.IsZero() && !end.IsZero() {
+		ref.TimeRange = &backend.TimeRange{Start: begin, End: end}
+	}
+
+	return ref
+}
+
+// decodeNewRelicState decodes a base64-encoded JSON state parameter, returning nil if it is not one
+func decodeNewRelicState(s string) map[string]any {
+	if s == "" {
+		return nil
+	}
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.URLEncoding, base64.RawStdEncoding, base64.RawURLEncoding} {
+		b, err := enc.DecodeString(s)
+		if err != nil {
+			continue
+		}
+		var state map[string]any
+		if err := json.Unmarshal(b, &state); err == nil {
+			return state
+		}
+	}
+	return nil
+}
+
+// collectValues flattens nested JSON objects into values keyed by their innermost key.
+// Keys already present are kept so that top-level query parameters take precedence.
+func collectValues(obj map[string]any, values map[string]any) {
+	for k, v := range obj {
+		if nested, ok := v.(map[string]any); ok {
+			collectValues(nested, values)
+			continue
+		}
+		if _, ok := values[k]; !ok {
+			values[k] = v
+		}
+	}
+}
+
+// parseGCPTraceURL extracts the trace from a GCP Trace explorer URL, either the legacy form
+// (/traces/list?project=p&tid=id) or the matrix parameter form (/traces/explorer;traceId=id;startTime=...)
+func parseGCPTraceURL(u *url.URL) *TraceRef {
+	q := u.Query()
+	ref := &TraceRef{
+		ProjectID: q.Get("project"),
+		TraceID:   q.Get("tid"),
+	}
+
+	params := map[string]string{}
+	for _, segment := range strings.Split(u.Path, "/") {
+		for _, param := range strings.Split(segment, ";")[1:] {
+			if k, v, ok := strings.Cut(param, "="); ok {
+				params[k] = v
+			}
+		}
+	}
+	if ref.TraceID == "" {
+		ref.TraceID = params["traceId"]
+	}
+
+	start, serr := time.Parse(time.RFC3339Nano, params["startTime"])
+	end, eerr := time.Parse(time.RFC3339Nano, params["endTime"])
+	if serr == nil && eerr == nil {
+		ref.TimeRange = &backend.TimeRange{Start: start, End: end}
+	}
+
+	return ref
+}
+
+// grafanaPane represents an Explore pane encoded as JSON in the left or panes parameters
+type grafanaPane struct {
+	Queries []struct {
+		Query string `json:"query"`
+	} `json:"queries"`
+	Range struct {
+		From string `json:"from"`
+		To   string `json:"to"`
+	} `json:"range"`
+}
+
+// parseGrafanaURL extracts the trace from a Grafana Explore URL. The trace ID is taken from the
+// first query that is a valid trace ID, e.g., a Tempo trace lookup, searching the left pane first
+// and then the panes in the order of their keys.
+func parseGrafanaURL(u *url.URL) *TraceRef {
+	q := u.Query()
+	ref := &TraceRef{}
+
+	var panes []grafanaPane
+	if left := q.Get("left"); left != "" {
+		var pane grafanaPane
+		if err := json.Unmarshal([]byte(left), &pane); err == nil {
+			panes = append(panes, pane)
+		}
+	}
+	if p := q.Get("panes"); p != "" {
+		var m map[string]grafanaPane
+		if err := json.Unmarshal([]byte(p), &m); err == nil {
+			// Map order is random, so the panes are searched in the order of their keys
+			for _, key := range slices.Sorted(maps.Keys(m)) {
+				panes = append(panes, m[key])
+			}
+		}
+	}
+
+	for _, pane := range panes {
+		for _, query := range pane.Queries {
+			if _, err := model.ParseTraceID(query.Query); err != nil {
+				continue
+			}
+			ref.TraceID = strings.TrimSpace(query.Query)
+
+			// Relative ranges such as now-1h are ignored as they no longer point to the trace
+			start, end := epochMilli(pane.Range.From), epochMilli(pane.Range.To)
+			if !start.IsZero() && !end.IsZero() {
+				ref.TimeRange = &backend.TimeRange{Start: start, End: end}
+			}
+			return ref
+		}
+	}
+
+	return ref
+}
+
+// parseJaegerURL extracts the trace ID from a Jaeger UI URL (/trace/<trace-id>)
+func parseJaegerURL(u *url.URL) *TraceRef {
+	_, after, _ := strings.Cut(u.Path, "/trace/")
+	id, _, _ := strings.Cut(after, "/")
+	return &TraceRef{TraceID: id}
+}
+
+// lastTraceIDSegment returns the last path segment that looks like a trace ID
+func lastTraceIDSegment(path string) string {
+	segments := strings.Split(path, "/")
+	for i := len(segments) - 1; i >= 0; i-- {
+		if traceIDSegmentPattern.MatchString(segments[i]) {
+			return segments[i]
+		}
+	}
+	return ""
+}
+
+func firstOf(values map[string]any, keys ...string) any {
+	for _, k := range keys {
+		if v, ok := values[k]; ok {
+			return v
+		}
+	}
+	return nil
+}
+
+// epochMilli converts epoch milliseconds given as a number or a numeric string to time,
+// returning the zero time for anything else
+func epochMilli(v any) time.Time {
+	if ms, ok := milliValue(v); ok {
+		return time.UnixMilli(ms)
+	}
+	return time.Time{}
+}
+
+// milliValue returns the milliseconds given as a JSON number or a numeric string
+func milliValue(v any) (int64, bool) {
+	switch ms := v.(type) {
+	case float64:
+		return int64(ms), true
+	case string:
+		if n, err := strconv.ParseInt(ms, 10, 64); err == nil {
+			return n, true
+		}
+	}
+	return 0, false
+}
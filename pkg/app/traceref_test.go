@@ -0,0 +1,100 @@
+package app
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestParseTraceRef(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		want      TraceRef
+		wantRange bool
+		wantErr   bool
+	}{
+		{
+			name:  "raw trace ID",
+			input: " 4bf92f3577b34da6a3ce929d0e0e4736 ",
+			want:  TraceRef{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736"},
+		},
+		{
+			name:      "New Relic URL",
+			input:     "https://one.newrelic.com/distributed-tracing/trace/4bf92f3577b34da6a3ce929d0e0e4736?account=123&begin=1700000000000&end=1700000060000",
+			want:      TraceRef{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", AccountID: 123},
+			wantRange: true,
+		},
+		{
+			name:    "host only ending with newrelic.com",
+			input:   "https://evilnewrelic.com/distributed-tracing?traceId=4bf92f3577b34da6a3ce929d0e0e4736&account=123",
+			wantErr: true,
+		},
+		{
+			name:  "GCP legacy URL",
+			input: "https://console.cloud.google.com/traces/list?project=my-project&tid=4bf92f3577b34da6a3ce929d0e0e4736",
+			want:  TraceRef{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", ProjectID: "my-project"},
+		},
+		{
+			name:      "GCP explorer URL",
+			input:     "https://console.cloud.google.com/traces/explorer;traceId=4bf92f3577b34da6a3ce929d0e0e4736;startTime=2024-01-01T00:00:00Z;endTime=2024-01-01T01:00:00Z?project=my-project",
+			want:      TraceRef{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", ProjectID: "my-project"},
+			wantRange: true,
+		},
+		{
+			name:      "Grafana Explore URL with a single pane",
+			input:     "https://grafana.example.com/explore?schemaVersion=1&panes=" + url.QueryEscape(`{"x1y":{"queries":[{"query":"4bf92f3577b34da6a3ce929d0e0e4736"}],"range":{"from":"1700000000000","to":"1700000060000"}}}`),
+			want:      TraceRef{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736"},
+			wantRange: true,
+		},
+		{
+			name: "Grafana Explore URL with several panes",
+			input: "https://grafana.example.com/explore?panes=" + url.QueryEscape(`{`+
+				`"zzz":{"queries":[{"query":"a3ce929d0e0e47364bf92f3577b34da6"}],"range":{"from":"now-1h","to":"now"}},`+
+				`"aaa":{"queries":[{"query":"{job=\"api\"}"}],"range":{"from":"now-1h","to":"now"}},`+
+				`"mmm":{"queries":[{"query":"4bf92f3577b34da6a3ce929d0e0e4736"}],"range":{"from":"1700000000000","to":"1700000060000"}}}`),
+			want:      TraceRef{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736"},
+			wantRange: true,
+		},
+		{
+			name:  "Grafana Explore URL with the left pane",
+			input: "https://grafana.example.com/explore?left=" + url.QueryEscape(`{"queries":[{"query":"4bf92f3577b34da6a3ce929d0e0e4736"}],"range":{"from":"now-1h","to":"now"}}`),
+			want:  TraceRef{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736"},
+		},
+		{
+			name:  "Jaeger URL",
+			input: "http://localhost:16686/trace/4bf92f3577b34da6a3ce929d0e0e4736",
+			want:  TraceRef{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736"},
+		},
+		{
+			name:    "unsupported URL",
+			input:   "https://example.com/dashboards",
+			wantErr: true,
+		},
+		{
+			name:    "URL without trace ID",
+			input:   "https://console.cloud.google.com/traces/list?project=my-project",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTraceRef(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTraceRef() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.TraceID != tt.want.TraceID || got.AccountID != tt.want.AccountID || got.ProjectID != tt.want.ProjectID {
+				t.Errorf("ParseTraceRef() = %+v, want %+v", got, tt.want)
+			}
+			if (got.TimeRange != nil) != tt.wantRange {
+				t.Fatalf("ParseTraceRef() time range = %v, want range %v", got.TimeRange, tt.wantRange)
+			}
+			if got.TimeRange != nil && got.TimeRange.End.Before(got.TimeRange.Start) {
+				t.Errorf("ParseTraceRef() time range ends before it starts: %v", got.TimeRange)
+			}
+		})
+	}
+}
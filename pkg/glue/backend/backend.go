@@ -2,24 +2,53 @@ package backend
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"time"
 
 	"github.com/ymtdzzz/telemetry-glue/pkg/app/model"
 )
 
 // SearchLogsRequest represents a request to search spans
 type SearchSpansRequest struct {
-	TraceID   string
+	TraceID   model.TraceID
 	TimeRange *TimeRange
 }
 
 // SearchLogsRequest represents a request to search logs
 type SearchLogsRequest struct {
-	TraceID   string
+	TraceID   model.TraceID
 	TimeRange *TimeRange
 }
 
+// LocateTraceRequest represents a request to discover the time range of a trace
+type LocateTraceRequest struct {
+	TraceID model.TraceID
+	// Lookback is how far back from now the trace is searched
+	Lookback time.Duration
+}
+
 // GlueBackend defines the interface for backends that can search both spans and logs
 type GlueBackend interface {
 	SearchSpans(ctx context.Context, req *SearchSpansRequest) (model.Spans, error)
 	SearchLogs(ctx context.Context, req *SearchLogsRequest) (model.Logs, error)
 }
+
+// TraceLocator is an optional capability of a GlueBackend that can discover
+// the time range of a trace from its ID only
+type TraceLocator interface {
+	LocateTrace(ctx context.Context, req *LocateTraceRequest) (*TimeRange, error)
+}
+
+// ErrTraceNotFound is returned by LocateTrace when the trace does not exist in the lookback window
+var ErrTraceNotFound = errors.New("trace not found")
+
+// TruncatedError is returned along with the fetched data when a backend could not fetch
+// all entries, so that callers can keep the partial result and report the truncation
+type TruncatedError struct {
+	Limit int
+}
+
+func (e *TruncatedError) Error() string {
+	return fmt.Sprintf("result was truncated at %d entries", e.Limit)
+}
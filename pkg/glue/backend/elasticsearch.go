@@ -0,0 +1,368 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jeremywohl/flatten/v2"
+	gconfig "github.com/ymtdzzz/telemetry-glue/pkg/app/config"
+	"github.com/ymtdzzz/telemetry-glue/pkg/app/model"
+)
+
+// esPageSize is the number of documents fetched per _search call
+const esPageSize = 1000
+
+// ElasticsearchBackend represents an Elasticsearch/OpenSearch backend
+type ElasticsearchBackend struct {
+	client         *http.Client
+	baseURL        string
+	username       string
+	password       string
+	apiKey         string
+	logIndex       string
+	spanIndex      string
+	traceIDField   string
+	traceIDFormat  model.TraceIDFormat
+	spanIDField    string
+	timestampField string
+	messageField   string
+}
+
+// NewElasticsearchBackend creates a new Elasticsearch backend
+func NewElasticsearchBackend(cfg *gconfig.ElasticsearchConfig) *ElasticsearchBackend {
+	return &ElasticsearchBackend{
+		client:         &http.Client{Timeout: 30 * time.Second},
+		baseURL:        strings.TrimRight(cfg.URL, "/"),
+		username:       cfg.Username,
+		password:       cfg.Password,
+		apiKey:         cfg.APIKey,
+		logIndex:       valueOrDefault(cfg.LogIndex, "logs-*"),
+		spanIndex:      valueOrDefault(cfg.SpanIndex, "traces-apm*"),
+		traceIDField:   valueOrDefault(cfg.TraceIDField, "trace.id"),
+		traceIDFormat:  model.TraceIDFormat(cfg.TraceIDFormat),
+		spanIDField:    valueOrDefault(cfg.SpanIDField, "span.id"),
+		timestampField: valueOrDefault(cfg.TimestampField, "@timestamp"),
+		messageField:   valueOrDefault(cfg.MessageField, "message"),
+	}
+}
+
+// esPITKeepAlive is how long the point in time is kept between two pages
+const esPITKeepAlive = "1m"
+
+// esSearchResponse represents the response of the _search API
+type esSearchResponse struct {
+	PITID string `json:"pit_id"`
+	Hits  struct {
+		Hits []struct {
+			Source map[string]any `json:"_source"`
+			Sort   []any          `json:"sort"`
+		} `json:"hits"`
+	} `json:"hits"`
+}
+
+func (e *ElasticsearchBackend) SearchSpans(ctx context.Context, req *SearchSpansRequest) (model.Spans, error) {
+	docs, err := e.search(ctx, e.spanIndex, req.TraceID.Format(e.traceIDFormat), req.TimeRange)
+	if err != nil {
+		return nil, err
+	}
+
+	var spans model.Spans
+	for _, doc := range docs {
+		spans = append(spans, e.convertSpan(doc))
+	}
+
+	return spans, nil
+}
+
+func (e *ElasticsearchBackend) SearchLogs(ctx context.Context, req *SearchLogsRequest) (model.Logs, error) {
+	docs, err := e.search(ctx, e.logIndex, req.TraceID.Format(e.traceIDFormat), req.TimeRange)
+	if err != nil {
+		return nil, err
+	}
+
+	var logs model.Logs
+	for _, doc := range docs {
+		logs = append(logs, e.convertLog(doc))
+	}
+
+	return logs, nil
+}
+
+// search returns the flattened sources of all documents matching the trace ID. When they do not
+// fit in a page, they are fetched again from a point in time and paged with search_after on the
+// _shard_doc tiebreaker, as the _doc order is not stable across shards and refreshes.
+func (e *ElasticsearchBackend) search(
+	ctx context.Context,
+	index string,
+	traceID string,
+	timeRange *TimeRange,
+) ([]map[string]any, error) {
+	filters := []any{
+		map[string]any{"term": map[string]any{e.traceIDField: traceID}},
+	}
+	if timeRange != nil {
+		filters = append(filters, map[string]any{
+			"range": map[string]any{
+				e.timestampField: map[string]any{
+					"gte":    timeRange.Start.UnixMilli(),
+					"lte":    timeRange.End.UnixMilli(),
+					"format": "epoch_millis",
+				},
+			},
+		})
+	}
+
+	query := map[string]any{
+		"size":  esPageSize,
+		"query": map[string]any{"bool": map[string]any{"filter": filters}},
+		"sort":  []any{map[string]any{e.timestampField: "asc"}},
+	}
+
+	log.Printf("Executing Elasticsearch query on %s: %s=%s", index, e.traceIDField, traceID)
+
+	resp, err := e.doSearch(ctx, index, query)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Hits.Hits) < esPageSize {
+		return flattenHits(resp, nil)
+	}
+
+	pitID, err := e.openPIT(ctx, index)
+	if err != nil {
+		// OpenSearch and Elasticsearch before 7.10 have no point in time API
+		log.Printf("Failed to open a point in time on %s, paging without it: %v", index, err)
+		query["sort"] = []any{
+			map[string]any{e.timestampField: "asc"},
+			map[string]any{"_doc": "asc"},
+		}
+		return e.searchAfter(ctx, index, query)
+	}
+
+	pit := map[string]any{"id": pitID, "keep_alive": esPITKeepAlive}
+	defer func() {
+		e.closePIT(context.WithoutCancel(ctx), pit["id"].(string))
+	}()
+
+	query["pit"] = pit
+	query["sort"] = []any{
+		map[string]any{e.timestampField: "asc"},
+		map[string]any{"_shard_doc": "asc"},
+	}
+	// The index is given by the point in time and must not be in the path
+	return e.searchAfter(ctx, "", query)
+}
+
+// searchAfter pages through all documents matching the query with search_after
+func (e *ElasticsearchBackend) searchAfter(ctx context.Context, index string, query map[string]any) ([]map[string]any, error) {
+	var docs []map[string]any
+
+	for {
+		resp, err := e.doSearch(ctx, index, query)
+		if err != nil {
+			return nil, err
+		}
+
+		docs, err = flattenHits(resp, docs)
+		if err != nil {
+			return nil, err
+		}
+
+		// The point in time ID may change between pages
+		if pit, ok := query["pit"].(map[string]any); ok && resp.PITID != "" {
+			pit["id"] = resp.PITID
+		}
+
+		hits := resp.Hits.Hits
+		if len(hits) < esPageSize {
+			break
+		}
+		query["search_after"] = hits[len(hits)-1].Sort
+	}
+
+	return docs, nil
+}
+
+// flattenHits appends the flattened sources of the hits to docs
+func flattenHits(resp *esSearchResponse, docs []map[string]any) ([]map[string]any, error) {
+	for _, hit := range resp.Hits.Hits {
+		doc, err := flatten.Flatten(hit.Source, "", flatten.DotStyle)
+		if err != nil {
+			return nil, fmt.Errorf("failed to flatten document: %w", err)
+		}
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
+
+// openPIT opens a point in time on the index and returns its ID
+func (e *ElasticsearchBackend) openPIT(ctx context.Context, index string) (string, error) {
+	endpoint := fmt.Sprintf("%s/%s/_pit?keep_alive=%s", e.baseURL, url.PathEscape(index), esPITKeepAlive)
+
+	var pitResp struct {
+		ID string `json:"id"`
+	}
+	if err := e.do(ctx, http.MethodPost, endpoint, nil, &pitResp); err != nil {
+		return "", err
+	}
+	if pitResp.ID == "" {
+		return "", errors.New("no point in time ID in the response")
+	}
+	return pitResp.ID, nil
+}
+
+// closePIT closes the point in time. It is only logged on failure as the point in time expires anyway.
+func (e *ElasticsearchBackend) closePIT(ctx context.Context, pitID string) {
+	if err := e.do(ctx, http.MethodDelete, e.baseURL+"/_pit", map[string]any{"id": pitID}, nil); err != nil {
+		log.Printf("Failed to close the Elasticsearch point in time: %v", err)
+	}
+}
+
+func (e *ElasticsearchBackend) doSearch(ctx context.Context, index string, query map[string]any) (*esSearchResponse, error) {
+	endpoint := e.baseURL + "/_search"
+	if index != "" {
+		endpoint = fmt.Sprintf("%s/%s/_search", e.baseURL, url.PathEscape(index))
+	}
+
+	var esResp esSearchResponse
+	if err := e.do(ctx, http.MethodPost, endpoint, query, &esResp); err != nil {
+		return nil, err
+	}
+	return &esResp, nil
+}
+
+// do sends the request with the JSON body, if any, and decodes the response into out, if any
+func (e *ElasticsearchBackend) do(ctx context.Context, method, endpoint string, body any, out any) error {
+	var reqBody io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to build Elasticsearch request: %w", err)
+		}
+		reqBody = bytes.NewReader(b)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, method, endpoint, reqBody)
+	if err != nil {
+		return fmt.Errorf("failed to create Elasticsearch request: %w", err)
+	}
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	if e.username != "" {
+		httpReq.SetBasicAuth(e.username, e.password)
+	}
+	if e.apiKey != "" {
+		httpReq.Header.Set("Authorization", "ApiKey "+e.apiKey)
+	}
+
+	resp, err := e.client.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to execute Elasticsearch request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected Elasticsearch response status: %s", resp.Status)
+	}
+
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return fmt.Errorf("failed to parse response: %w", err)
+		}
+	}
+	return nil
+}
+
+// convertLog converts a flattened document to a log model
+func (e *ElasticsearchBackend) convertLog(doc map[string]any) model.Log {
+	l := model.Log{
+		Timestamp:  parseESTimestamp(doc[e.timestampField]),
+		TraceID:    stringValue(doc[e.traceIDField]),
+		SpanID:     stringValue(doc[e.spanIDField]),
+		Message:    stringValue(doc[e.messageField]),
+		Attributes: make(map[string]any),
+	}
+
+	for k, v := range doc {
+		switch k {
+		case e.timestampField, e.traceIDField, e.spanIDField, e.messageField:
+		default:
+			l.Attributes[k] = v
+		}
+	}
+
+	return l
+}
+
+// convertSpan converts a flattened APM document (transaction or span) to a span model
+func (e *ElasticsearchBackend) convertSpan(doc map[string]any) model.Span {
+	attrs := maps.Clone(doc)
+
+	span := model.Span{
+		TraceID:      takeString(attrs, e.traceIDField),
+		SpanID:       takeString(attrs, "span.id", "transaction.id", "spanId"),
+		ParentSpanID: takeString(attrs, "parent.id", "parentSpanId"),
+		Name:         takeString(attrs, "span.name", "transaction.name", "name"),
+		ServiceName:  takeString(attrs, "service.name", "serviceName"),
+		Kind:         model.ParseSpanKind(takeString(attrs, "span.kind", "kind")),
+		StartTime:    parseESTimestamp(doc[e.timestampField]),
+	}
+	delete(attrs, e.timestampField)
+
+	if us, ok := takeFloat(attrs, "span.duration.us"); ok {
+		span.Duration = time.Duration(us) * time.Microsecond
+	} else if us, ok := takeFloat(attrs, "transaction.duration.us"); ok {
+		span.Duration = time.Duration(us) * time.Microsecond
+	} else if ns, ok := takeFloat(attrs, "durationInNanos"); ok {
+		span.Duration = time.Duration(ns)
+	}
+
+	switch takeString(attrs, "event.outcome") {
+	case "failure":
+		span.Status = model.SpanStatusError
+	case "success":
+		span.Status = model.SpanStatusOK
+	}
+
+	span.Attributes = attrs
+
+	return span
+}
+
+// parseESTimestamp parses a timestamp given either as a date string or as epoch milliseconds
+func parseESTimestamp(v any) time.Time {
+	switch ts := v.(type) {
+	case string:
+		if t, err := time.Parse(time.RFC3339Nano, ts); err == nil {
+			return t
+		}
+	case float64:
+		return time.UnixMilli(int64(ts))
+	}
+	return time.Time{}
+}
+
+func stringValue(v any) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+func valueOrDefault(value, defaultValue string) string {
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
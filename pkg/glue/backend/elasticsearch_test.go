@@ -0,0 +1,141 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	gconfig "github.com/ymtdzzz/telemetry-glue/pkg/app/config"
+	"github.com/ymtdzzz/telemetry-glue/pkg/app/model"
+)
+
+// fakeElasticsearch serves documents that all share the same timestamp, so that
+// paging relies on the tiebreaker sort field
+type fakeElasticsearch struct {
+	t          *testing.T
+	docs       int
+	pit        bool
+	tiebreaker string // the tiebreaker expected in the paging requests
+	closed     bool
+}
+
+func (f *fakeElasticsearch) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/logs-*/_pit":
+		if !f.pit {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":"pit-1"}`))
+	case r.Method == http.MethodDelete && r.URL.Path == "/_pit":
+		var body struct {
+			ID string `json:"id"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body.ID != "pit-2" {
+			f.t.Errorf("closed point in time %s, want the latest pit-2", body.ID)
+		}
+		f.closed = true
+	case r.Method == http.MethodPost && (r.URL.Path == "/logs-*/_search" || r.URL.Path == "/_search"):
+		f.search(w, r)
+	default:
+		f.t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (f *fakeElasticsearch) search(w http.ResponseWriter, r *http.Request) {
+	var query struct {
+		Size        int              `json:"size"`
+		Sort        []map[string]any `json:"sort"`
+		SearchAfter []float64        `json:"search_after"`
+		PIT         *struct {
+			ID string `json:"id"`
+		} `json:"pit"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&query); err != nil {
+		f.t.Fatal(err)
+	}
+
+	paging := len(query.Sort) > 1
+	if paging {
+		if _, ok := query.Sort[1][f.tiebreaker]; !ok {
+			f.t.Errorf("tiebreaker = %v, want %s", query.Sort[1], f.tiebreaker)
+		}
+		if (query.PIT != nil) != (r.URL.Path == "/_search") {
+			f.t.Errorf("point in time = %v on %s", query.PIT, r.URL.Path)
+		}
+	}
+
+	start := 0
+	if len(query.SearchAfter) == 2 {
+		start = int(query.SearchAfter[1]) + 1
+	}
+
+	type hit struct {
+		Source map[string]any `json:"_source"`
+		Sort   []any          `json:"sort"`
+	}
+	var hits []hit
+	for i := start; i < f.docs && len(hits) < query.Size; i++ {
+		hits = append(hits, hit{
+			Source: map[string]any{"trace.id": "t", "@timestamp": float64(1700000000000), "message": fmt.Sprintf("log %d", i)},
+			Sort:   []any{1700000000000, i},
+		})
+	}
+
+	resp := map[string]any{"hits": map[string]any{"hits": hits}}
+	if query.PIT != nil {
+		resp["pit_id"] = "pit-2"
+	}
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+func TestElasticsearchBackendSearchLogs(t *testing.T) {
+	traceID, err := model.ParseTraceID("4bf92f3577b34da6a3ce929d0e0e4736")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		docs       int
+		pit        bool
+		tiebreaker string
+		wantClosed bool
+	}{
+		{name: "single page", docs: 10, pit: true},
+		{name: "pages from a point in time", docs: 2*esPageSize + 10, pit: true, tiebreaker: "_shard_doc", wantClosed: true},
+		{name: "exactly one page", docs: esPageSize, pit: true, tiebreaker: "_shard_doc", wantClosed: true},
+		{name: "no point in time API", docs: esPageSize + 10, tiebreaker: "_doc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeElasticsearch{t: t, docs: tt.docs, pit: tt.pit, tiebreaker: tt.tiebreaker}
+			server := httptest.NewServer(fake)
+			defer server.Close()
+
+			e := NewElasticsearchBackend(&gconfig.ElasticsearchConfig{URL: server.URL})
+			logs, err := e.SearchLogs(context.Background(), &SearchLogsRequest{TraceID: traceID})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(logs) != tt.docs {
+				t.Fatalf("SearchLogs() returned %d logs, want %d", len(logs), tt.docs)
+			}
+			for i, l := range logs {
+				if want := fmt.Sprintf("log %d", i); l.Message != want {
+					t.Fatalf("logs[%d] = %s, want %s", i, l.Message, want)
+				}
+			}
+			if fake.closed != tt.wantClosed {
+				t.Errorf("point in time closed = %v, want %v", fake.closed, tt.wantClosed)
+			}
+		})
+	}
+}
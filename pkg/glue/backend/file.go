@@ -0,0 +1,135 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	gconfig "github.com/ymtdzzz/telemetry-glue/pkg/app/config"
+	"github.com/ymtdzzz/telemetry-glue/pkg/app/model"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// FileBackend represents a backend reading OTLP JSON files from disk
+type FileBackend struct {
+	spanPath string
+	logPath  string
+}
+
+// NewFileBackend creates a new file backend
+func NewFileBackend(cfg *gconfig.FileConfig) *FileBackend {
+	return &FileBackend{
+		spanPath: cfg.SpanPath,
+		logPath:  cfg.LogPath,
+	}
+}
+
+func (f *FileBackend) SearchSpans(ctx context.Context, req *SearchSpansRequest) (model.Spans, error) {
+	if f.spanPath == "" {
+		return nil, errors.New("span file path is not configured")
+	}
+
+	var spans model.Spans
+
+	err := readOTLPFiles(f.spanPath, func() proto.Message {
+		return &coltracepb.ExportTraceServiceRequest{}
+	}, func(msg proto.Message) {
+		for _, span := range convertOTLPResourceSpans(msg.(*coltracepb.ExportTraceServiceRequest).GetResourceSpans()) {
+			if !strings.EqualFold(span.TraceID, req.TraceID.W3C()) {
+				continue
+			}
+			if !req.TimeRange.contains(span.StartTime) {
+				continue
+			}
+			spans = append(spans, span)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return spans, nil
+}
+
+func (f *FileBackend) SearchLogs(ctx context.Context, req *SearchLogsRequest) (model.Logs, error) {
+	if f.logPath == "" {
+		return nil, errors.New("log file path is not configured")
+	}
+
+	var logs model.Logs
+
+	err := readOTLPFiles(f.logPath, func() proto.Message {
+		return &collogspb.ExportLogsServiceRequest{}
+	}, func(msg proto.Message) {
+		for _, l := range convertOTLPResourceLogs(msg.(*collogspb.ExportLogsServiceRequest).GetResourceLogs()) {
+			if !strings.EqualFold(l.TraceID, req.TraceID.W3C()) {
+				continue
+			}
+			if !req.TimeRange.contains(l.Timestamp) {
+				continue
+			}
+			logs = append(logs, l)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sortLogsByTimestamp(logs)
+
+	return logs, nil
+}
+
+// readOTLPFiles decodes every OTLP JSON document in the files matching the pattern.
+// Each file may hold a single document or newline-delimited documents.
+func readOTLPFiles(pattern string, newMessage func() proto.Message, handle func(proto.Message)) error {
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		return fmt.Errorf("invalid file pattern %s: %w", pattern, err)
+	}
+	if len(paths) == 0 {
+		return fmt.Errorf("no files match %s", pattern)
+	}
+
+	for _, path := range paths {
+		log.Printf("Reading OTLP file: %s", path)
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		for {
+			var raw json.RawMessage
+			if err := decoder.Decode(&raw); err == io.EOF {
+				break
+			} else if err != nil {
+				return fmt.Errorf("failed to parse %s: %w", path, err)
+			}
+
+			normalized, err := normalizeOTLPJSON(raw)
+			if err != nil {
+				return fmt.Errorf("failed to parse %s: %w", path, err)
+			}
+
+			msg := newMessage()
+			if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(normalized, msg); err != nil {
+				return fmt.Errorf("failed to parse %s: %w", path, err)
+			}
+			handle(msg)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,148 @@
+package backend
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	gconfig "github.com/ymtdzzz/telemetry-glue/pkg/app/config"
+	"github.com/ymtdzzz/telemetry-glue/pkg/app/model"
+)
+
+func TestFileBackendSearchSpans(t *testing.T) {
+	traceID, err := model.ParseTraceID("4bf92f3577b34da6a3ce929d0e0e4736")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		spanPath  string
+		timeRange *TimeRange
+		wantNames []string
+		wantErr   bool
+	}{
+		{
+			name:      "newline-delimited documents",
+			spanPath:  "testdata/otlp_traces.jsonl",
+			wantNames: []string{"GET /orders", "SELECT orders"},
+		},
+		{
+			name:      "glob pattern",
+			spanPath:  "testdata/otlp_*.jsonl",
+			wantNames: []string{"GET /orders", "SELECT orders"},
+		},
+		{
+			name:      "time range",
+			spanPath:  "testdata/otlp_traces.jsonl",
+			timeRange: &TimeRange{Start: time.Unix(1700000000, 50_000_000), End: time.Unix(1700000001, 0)},
+			wantNames: []string{"SELECT orders"},
+		},
+		{
+			name:     "no matching files",
+			spanPath: "testdata/missing-*.json",
+			wantErr:  true,
+		},
+		{
+			name:    "not configured",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := NewFileBackend(&gconfig.FileConfig{SpanPath: tt.spanPath})
+			spans, err := f.SearchSpans(context.Background(), &SearchSpansRequest{TraceID: traceID, TimeRange: tt.timeRange})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SearchSpans() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			var names []string
+			for _, s := range spans {
+				names = append(names, s.Name)
+			}
+			if len(names) != len(tt.wantNames) {
+				t.Fatalf("SearchSpans() = %v, want %v", names, tt.wantNames)
+			}
+			for i := range names {
+				if names[i] != tt.wantNames[i] {
+					t.Errorf("SearchSpans() = %v, want %v", names, tt.wantNames)
+				}
+			}
+		})
+	}
+}
+
+func TestFileBackendSearchSpansConversion(t *testing.T) {
+	traceID, err := model.ParseTraceID("4bf92f3577b34da6a3ce929d0e0e4736")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spans, err := NewFileBackend(&gconfig.FileConfig{SpanPath: "testdata/otlp_traces.jsonl"}).
+		SearchSpans(context.Background(), &SearchSpansRequest{TraceID: traceID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(spans) != 2 {
+		t.Fatalf("SearchSpans() returned %d spans, want 2", len(spans))
+	}
+
+	root, child := spans[0], spans[1]
+	if root.SpanID != "a3ce929d0e0e4736" || root.ServiceName != "frontend" || root.Kind != model.SpanKindServer ||
+		root.Status != model.SpanStatusError || root.StatusMessage != "internal error" || root.Duration != 250*time.Millisecond {
+		t.Errorf("unexpected root span: %+v", root)
+	}
+	if child.ParentSpanID != root.SpanID || child.ServiceName != "orders" || child.Kind != model.SpanKindClient ||
+		!child.StartTime.Equal(time.Unix(0, 1700000000100000000)) {
+		t.Errorf("unexpected child span: %+v", child)
+	}
+}
+
+func TestFileBackendSearchLogs(t *testing.T) {
+	traceID, err := model.ParseTraceID("4bf92f3577b34da6a3ce929d0e0e4736")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		timeRange    *TimeRange
+		wantMessages []string
+	}{
+		{
+			name:         "sorted by timestamp",
+			wantMessages: []string{"query started", "query failed"},
+		},
+		{
+			name:         "time range",
+			timeRange:    &TimeRange{Start: time.Unix(1700000000, 150_000_000), End: time.Unix(1700000001, 0)},
+			wantMessages: []string{"query failed"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := NewFileBackend(&gconfig.FileConfig{LogPath: "testdata/otlp_logs.json"})
+			logs, err := f.SearchLogs(context.Background(), &SearchLogsRequest{TraceID: traceID, TimeRange: tt.timeRange})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(logs) != len(tt.wantMessages) {
+				t.Fatalf("SearchLogs() returned %d logs, want %d", len(logs), len(tt.wantMessages))
+			}
+			for i, l := range logs {
+				if l.Message != tt.wantMessages[i] {
+					t.Errorf("logs[%d].Message = %s, want %s", i, l.Message, tt.wantMessages[i])
+				}
+				if l.SpanID != "00f067aa0ba902b7" || l.Attributes["service.name"] != "orders" {
+					t.Errorf("unexpected log: %+v", l)
+				}
+			}
+			if !logs[len(logs)-1].IsError() {
+				t.Error("the ERROR log should be an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package backend
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ymtdzzz/telemetry-glue/pkg/app/model"
+)
+
+func TestGCPBackendBuildFilter(t *testing.T) {
+	traceID, err := model.ParseTraceID("4bf92f3577b34da6a3ce929d0e0e4736")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 250_000_000, time.UTC)
+	end := time.Date(2024, 1, 1, 0, 0, 1, 750_000_000, time.UTC)
+
+	tests := []struct {
+		name      string
+		timeRange *TimeRange
+		want      string
+	}{
+		{
+			name: "without time range",
+			want: `trace="projects/p/traces/4bf92f3577b34da6a3ce929d0e0e4736"`,
+		},
+		{
+			name:      "keeps sub-second bounds",
+			timeRange: &TimeRange{Start: start, End: end},
+			want:      `trace="projects/p/traces/4bf92f3577b34da6a3ce929d0e0e4736" AND timestamp>="2024-01-01T00:00:00.25Z" AND timestamp<="2024-01-01T00:00:01.75Z"`,
+		},
+		{
+			name:      "start only",
+			timeRange: &TimeRange{Start: start},
+			want:      `trace="projects/p/traces/4bf92f3577b34da6a3ce929d0e0e4736" AND timestamp>="2024-01-01T00:00:00.25Z"`,
+		},
+	}
+
+	g := &GCPBackend{projectID: "p"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := g.buildFilter(&SearchLogsRequest{TraceID: traceID, TimeRange: tt.timeRange})
+			if got != tt.want {
+				t.Errorf("buildFilter() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	gconfig "github.com/ymtdzzz/telemetry-glue/pkg/app/config"
+	"github.com/ymtdzzz/telemetry-glue/pkg/app/model"
+)
+
+// JaegerBackend represents a Jaeger Query API backend
+type JaegerBackend struct {
+	client  *http.Client
+	baseURL string
+	located locatedSpans
+}
+
+// NewJaegerBackend creates a new Jaeger backend
+func NewJaegerBackend(cfg *gconfig.JaegerConfig) *JaegerBackend {
+	return &JaegerBackend{
+		client:  &http.Client{Timeout: 30 * time.Second},
+		baseURL: strings.TrimRight(cfg.URL, "/"),
+	}
+}
+
+// jaegerResponse represents the response of the Jaeger Query API
+type jaegerResponse struct {
+	Data   []jaegerTrace `json:"data"`
+	Errors []struct {
+		Code int    `json:"code"`
+		Msg  string `json:"msg"`
+	} `json:"errors"`
+}
+
+type jaegerTrace struct {
+	TraceID   string                   `json:"traceID"`
+	Spans     []jaegerSpan             `json:"spans"`
+	Processes map[string]jaegerProcess `json:"processes"`
+}
+
+type jaegerSpan struct {
+	TraceID       string            `json:"traceID"`
+	SpanID        string            `json:"spanID"`
+	OperationName string            `json:"operationName"`
+	References    []jaegerReference `json:"references"`
+	StartTime     int64             `json:"startTime"` // microseconds since epoch
+	Duration      int64             `json:"duration"`  // microseconds
+	Tags          []jaegerKeyValue  `json:"tags"`
+	Logs          []jaegerLog       `json:"logs"`
+	ProcessID     string            `json:"processID"`
+	Warnings      []string          `json:"warnings"`
+}
+
+type jaegerReference struct {
+	RefType string `json:"refType"`
+	TraceID string `json:"traceID"`
+	SpanID  string `json:"spanID"`
+}
+
+type jaegerKeyValue struct {
+	Key   string `json:"key"`
+	Type  string `json:"type"`
+	Value any    `json:"value"`
+}
+
+type jaegerLog struct {
+	Timestamp int64            `json:"timestamp"` // microseconds since epoch
+	Fields    []jaegerKeyValue `json:"fields"`
+}
+
+type jaegerProcess struct {
+	ServiceName string           `json:"serviceName"`
+	Tags        []jaegerKeyValue `json:"tags"`
+}
+
+func (j *JaegerBackend) SearchSpans(ctx context.Context, req *SearchSpansRequest) (model.Spans, error) {
+	if spans, ok := j.located.load(req); ok {
+		return spans, nil
+	}
+	return j.fetchSpans(ctx, req)
+}
+
+// fetchSpans downloads the trace from Jaeger
+func (j *JaegerBackend) fetchSpans(ctx context.Context, req *SearchSpansRequest) (model.Spans, error) {
+	endpoint := fmt.Sprintf("%s/api/traces/%s", j.baseURL, req.TraceID.Hex())
+	if req.TimeRange != nil {
+		query := url.Values{}
+		query.Set("start", strconv.FormatInt(req.TimeRange.Start.UnixMicro(), 10))
+		query.Set("end", strconv.FormatInt(req.TimeRange.End.UnixMicro(), 10))
+		endpoint += "?" + query.Encode()
+	}
+
+	log.Printf("Executing Jaeger query: %s", endpoint)
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Jaeger request: %w", err)
+	}
+
+	resp, err := j.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute Jaeger request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Jaeger returns 404 when the trace is not found
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected Jaeger response status: %s", resp.Status)
+	}
+
+	var jresp jaegerResponse
+	if err := json.NewDecoder(resp.Body).Decode(&jresp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if len(jresp.Errors) > 0 {
+		return nil, fmt.Errorf("jaeger returned an error: %s", jresp.Errors[0].Msg)
+	}
+
+	var spans model.Spans
+
+	for _, trace := range jresp.Data {
+		for _, s := range trace.Spans {
+			spans = append(spans, convertJaegerSpan(&s, trace.Processes[s.ProcessID]))
+		}
+	}
+
+	return spans, nil
+}
+
+// LocateTrace discovers the time range of the trace by fetching it within the lookback window.
+// The fetched spans are kept and returned by the following SearchSpans for the trace.
+func (j *JaegerBackend) LocateTrace(ctx context.Context, req *LocateTraceRequest) (*TimeRange, error) {
+	return j.located.locate(ctx, req, j.fetchSpans)
+}
+
+func (j *JaegerBackend) SearchLogs(ctx context.Context, req *SearchLogsRequest) (model.Logs, error) {
+	return nil, errors.New("not implemented")
+}
+
+// convertJaegerSpan converts a Jaeger span and its process to a span model
+func convertJaegerSpan(s *jaegerSpan, process jaegerProcess) model.Span {
+	attrs := make(map[string]any)
+	for _, tag := range process.Tags {
+		attrs["process."+tag.Key] = tag.Value
+	}
+	for _, tag := range s.Tags {
+		attrs[tag.Key] = tag.Value
+	}
+
+	span := model.Span{
+		TraceID:       s.TraceID,
+		SpanID:        s.SpanID,
+		ServiceName:   process.ServiceName,
+		Name:          s.OperationName,
+		Kind:          model.ParseSpanKind(takeString(attrs, "span.kind")),
+		StartTime:     time.UnixMicro(s.StartTime),
+		Duration:      time.Duration(s.Duration) * time.Microsecond,
+		Status:        model.ParseSpanStatus(takeString(attrs, "otel.status_code")),
+		StatusMessage: takeString(attrs, "otel.status_description"),
+	}
+
+	var references []string
+	for _, ref := range s.References {
+		if ref.RefType == "CHILD_OF" && ref.TraceID == s.TraceID && span.ParentSpanID == "" {
+			span.ParentSpanID = ref.SpanID
+			continue
+		}
+		references = append(references, fmt.Sprintf("%s:%s", ref.RefType, ref.SpanID))
+	}
+	if len(references) > 0 {
+		attrs["references"] = references
+	}
+
+	var events []map[string]any
+	for _, l := range s.Logs {
+		event := map[string]any{
+			"timestamp": time.UnixMicro(l.Timestamp).Format(time.RFC3339Nano),
+		}
+		for _, field := range l.Fields {
+			event[field.Key] = field.Value
+		}
+		events = append(events, event)
+	}
+	if len(events) > 0 {
+		attrs["events"] = events
+	}
+
+	if len(s.Warnings) > 0 {
+		attrs["warnings"] = s.Warnings
+	}
+
+	span.Attributes = attrs
+
+	return span
+}
@@ -0,0 +1,209 @@
+package backend
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	gconfig "github.com/ymtdzzz/telemetry-glue/pkg/app/config"
+	"github.com/ymtdzzz/telemetry-glue/pkg/app/model"
+)
+
+func TestJaegerBackendSearchSpans(t *testing.T) {
+	recorded, err := os.ReadFile("testdata/jaeger_trace.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	traceID, err := model.ParseTraceID("4bf92f3577b34da6a3ce929d0e0e4736")
+	if err != nil {
+		t.Fatal(err)
+	}
+	timeRange := &TimeRange{Start: time.UnixMicro(1699999990000000), End: time.UnixMicro(1700000010000000)}
+
+	tests := []struct {
+		name      string
+		status    int
+		body      string
+		timeRange *TimeRange
+		wantQuery string
+		wantSpans int
+		wantErr   bool
+	}{
+		{
+			name:      "recorded trace",
+			status:    http.StatusOK,
+			body:      string(recorded),
+			wantSpans: 2,
+		},
+		{
+			name:      "with time range",
+			status:    http.StatusOK,
+			body:      string(recorded),
+			timeRange: timeRange,
+			wantQuery: "end=1700000010000000&start=1699999990000000",
+			wantSpans: 2,
+		},
+		{
+			name:   "trace not found",
+			status: http.StatusNotFound,
+			body:   `{"data":null,"errors":[{"code":404,"msg":"trace not found"}]}`,
+		},
+		{
+			name:    "error in response",
+			status:  http.StatusOK,
+			body:    `{"data":null,"errors":[{"code":500,"msg":"storage unavailable"}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "unexpected status",
+			status:  http.StatusBadGateway,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/api/traces/4bf92f3577b34da6a3ce929d0e0e4736" {
+					t.Errorf("unexpected path: %s", r.URL.Path)
+				}
+				if r.URL.RawQuery != tt.wantQuery {
+					t.Errorf("query = %s, want %s", r.URL.RawQuery, tt.wantQuery)
+				}
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			j := NewJaegerBackend(&gconfig.JaegerConfig{URL: server.URL + "/"})
+			spans, err := j.SearchSpans(context.Background(), &SearchSpansRequest{TraceID: traceID, TimeRange: tt.timeRange})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SearchSpans() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(spans) != tt.wantSpans {
+				t.Errorf("SearchSpans() returned %d spans, want %d", len(spans), tt.wantSpans)
+			}
+		})
+	}
+}
+
+func TestConvertJaegerSpan(t *testing.T) {
+	recorded, err := os.ReadFile("testdata/jaeger_trace.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(recorded)
+	}))
+	defer server.Close()
+
+	traceID, err := model.ParseTraceID("4bf92f3577b34da6a3ce929d0e0e4736")
+	if err != nil {
+		t.Fatal(err)
+	}
+	spans, err := NewJaegerBackend(&gconfig.JaegerConfig{URL: server.URL}).SearchSpans(context.Background(), &SearchSpansRequest{TraceID: traceID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := model.Spans{
+		{
+			TraceID:       "4bf92f3577b34da6a3ce929d0e0e4736",
+			SpanID:        "a3ce929d0e0e4736",
+			ServiceName:   "frontend",
+			Name:          "GET /orders",
+			Kind:          model.SpanKindServer,
+			StartTime:     time.UnixMicro(1700000000000000),
+			Duration:      250 * time.Millisecond,
+			Status:        model.SpanStatusError,
+			StatusMessage: "internal error",
+			Attributes: map[string]any{
+				"process.hostname": "frontend-1",
+				"http.status_code": float64(500),
+			},
+		},
+		{
+			TraceID:      "4bf92f3577b34da6a3ce929d0e0e4736",
+			SpanID:       "00f067aa0ba902b7",
+			ParentSpanID: "a3ce929d0e0e4736",
+			ServiceName:  "orders",
+			Name:         "SELECT orders",
+			Kind:         model.SpanKindClient,
+			StartTime:    time.UnixMicro(1700000000100000),
+			Duration:     120 * time.Millisecond,
+			Attributes: map[string]any{
+				"db.system":  "postgresql",
+				"references": []string{"FOLLOWS_FROM:1111111111111111"},
+				"events": []map[string]any{
+					{
+						"timestamp": time.UnixMicro(1700000000200000).Format(time.RFC3339Nano),
+						"event":     "error",
+						"message":   "connection reset",
+					},
+				},
+				"warnings": []string{"clock skew adjustment disabled"},
+			},
+		},
+	}
+
+	if !reflect.DeepEqual(spans, want) {
+		t.Errorf("SearchSpans() = %+v, want %+v", spans, want)
+	}
+	if !spans[1].IsError() {
+		t.Error("the span with an error log should be failing")
+	}
+}
+
+func TestJaegerBackendLocateTrace(t *testing.T) {
+	recorded, err := os.ReadFile("testdata/jaeger_trace.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	traceID, err := model.ParseTraceID("4bf92f3577b34da6a3ce929d0e0e4736")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	requests := 0
+	var start, end int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		start, _ = strconv.ParseInt(r.URL.Query().Get("start"), 10, 64)
+		end, _ = strconv.ParseInt(r.URL.Query().Get("end"), 10, 64)
+		_, _ = w.Write(recorded)
+	}))
+	defer server.Close()
+
+	j := NewJaegerBackend(&gconfig.JaegerConfig{URL: server.URL})
+	lookback := 24 * time.Hour
+	timeRange, err := j.LocateTrace(context.Background(), &LocateTraceRequest{TraceID: traceID, Lookback: lookback})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := time.Duration(end-start) * time.Microsecond; got != lookback {
+		t.Errorf("located the trace in a window of %v, want the lookback of %v", got, lookback)
+	}
+	if want := time.UnixMicro(1700000000000000).Add(-traceTimeRangePadding); !timeRange.Start.Equal(want) {
+		t.Errorf("LocateTrace() start = %v, want %v", timeRange.Start, want)
+	}
+	if want := time.UnixMicro(1700000000250000).Add(traceTimeRangePadding); !timeRange.End.Equal(want) {
+		t.Errorf("LocateTrace() end = %v, want %v", timeRange.End, want)
+	}
+
+	spans, err := j.SearchSpans(context.Background(), &SearchSpansRequest{TraceID: traceID, TimeRange: timeRange})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(spans) != 2 {
+		t.Errorf("SearchSpans() returned %d spans, want 2", len(spans))
+	}
+	if requests != 1 {
+		t.Errorf("the trace was downloaded %d times, want 1", requests)
+	}
+}
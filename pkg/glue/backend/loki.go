@@ -0,0 +1,317 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	gconfig "github.com/ymtdzzz/telemetry-glue/pkg/app/config"
+	"github.com/ymtdzzz/telemetry-glue/pkg/app/model"
+)
+
+const (
+	// lokiDefaultQuery is the LogQL template used when no query is configured
+	lokiDefaultQuery = `{job=~".+"} |= "{{.TraceID}}"`
+	// lokiPageSize is the number of log lines fetched per query_range call
+	lokiPageSize = 1000
+)
+
+// lokiTraceIDKeys and lokiSpanIDKeys are label or structured metadata keys holding the trace and span IDs
+var (
+	lokiTraceIDKeys = []string{"trace_id", "traceID", "traceId", "trace.id"}
+	lokiSpanIDKeys  = []string{"span_id", "spanID", "spanId", "span.id"}
+)
+
+// LokiBackend represents a Grafana Loki backend
+type LokiBackend struct {
+	client      *http.Client
+	baseURL     string
+	tenantID    string
+	username    string
+	password    string
+	bearerToken string
+	query       *template.Template
+}
+
+// NewLokiBackend creates a new Loki backend
+func NewLokiBackend(cfg *gconfig.LokiConfig) (*LokiBackend, error) {
+	queryTemplate := cfg.Query
+	if queryTemplate == "" {
+		queryTemplate = lokiDefaultQuery
+	}
+	query, err := template.New("loki").Parse(queryTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Loki query template: %w", err)
+	}
+
+	return &LokiBackend{
+		client:      &http.Client{Timeout: 30 * time.Second},
+		baseURL:     strings.TrimRight(cfg.URL, "/"),
+		tenantID:    cfg.TenantID,
+		username:    cfg.Username,
+		password:    cfg.Password,
+		bearerToken: cfg.BearerToken,
+		query:       query,
+	}, nil
+}
+
+// lokiResponse represents the response of the Loki query_range API
+type lokiResponse struct {
+	Status string `json:"status"`
+	Data   struct {
+		ResultType string       `json:"resultType"`
+		Result     []lokiStream `json:"result"`
+	} `json:"data"`
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	// Values holds [<unix epoch in nanoseconds>, <log line>, <metadata (optional)>]
+	Values [][]json.RawMessage `json:"values"`
+}
+
+// lokiEntryMetadata represents the categorized labels of a log line
+type lokiEntryMetadata struct {
+	StructuredMetadata map[string]string `json:"structuredMetadata"`
+	Parsed             map[string]string `json:"parsed"`
+}
+
+func (l *LokiBackend) SearchSpans(ctx context.Context, req *SearchSpansRequest) (model.Spans, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (l *LokiBackend) SearchLogs(ctx context.Context, req *SearchLogsRequest) (model.Logs, error) {
+	query, err := l.buildQuery(req.TraceID)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Executing LogQL query: %s", query)
+
+	var (
+		logs  model.Logs
+		start time.Time
+		end   time.Time
+	)
+	if req.TimeRange != nil {
+		start = req.TimeRange.Start
+		end = req.TimeRange.End
+	}
+
+	// seen counts the entries fetched at the start timestamp, which the next page fetches again
+	seen := map[string]int{}
+	for {
+		page, err := l.queryRange(ctx, query, start, end)
+		if err != nil {
+			return nil, err
+		}
+
+		added := 0
+		occurrences := map[string]int{}
+		for _, entry := range page {
+			if entry.Timestamp.Equal(start) {
+				// Identical entries may legitimately occur several times, so only as many
+				// occurrences as were fetched by the previous pages are skipped
+				key := lokiEntryKey(entry)
+				occurrences[key]++
+				if occurrences[key] <= seen[key] {
+					continue
+				}
+			}
+			logs = append(logs, entry)
+			added++
+		}
+
+		if len(page) < lokiPageSize {
+			break
+		}
+
+		// Entries are returned in ascending order, so the next page starts at the latest timestamp
+		// of the page as more entries may share it. The cursor cannot move forward when a whole
+		// page shares the same timestamp.
+		last := page[len(page)-1].Timestamp
+		if added == 0 {
+			return logs, &TruncatedError{Limit: len(logs)}
+		}
+		if !last.Equal(start) {
+			seen = map[string]int{}
+		}
+		counts := map[string]int{}
+		for _, entry := range page {
+			if entry.Timestamp.Equal(last) {
+				key := lokiEntryKey(entry)
+				counts[key]++
+				seen[key] = max(seen[key], counts[key])
+			}
+		}
+		start = last
+	}
+
+	return logs, nil
+}
+
+// lokiQueryParams holds the trace ID forms available to the LogQL template
+type lokiQueryParams struct {
+	TraceID        string // as given by users
+	TraceIDW3C     string // 32-hex W3C form
+	TraceIDHex     string // 16-hex form for 64-bit IDs, 32-hex otherwise
+	XRayTraceID    string
+	DatadogTraceID string
+}
+
+// buildQuery renders the LogQL template with the trace ID in each supported format.
+// The trace ID given by users is escaped for a string literal. The normalized forms
+// only contain hex digits, digits and dashes, so they are safe as is.
+func (l *LokiBackend) buildQuery(traceID model.TraceID) (string, error) {
+	params := lokiQueryParams{
+		TraceID:        strings.NewReplacer(`\`, `\\`, `"`, `\"`, "`", "").Replace(traceID.Raw()),
+		TraceIDW3C:     traceID.W3C(),
+		TraceIDHex:     traceID.Hex(),
+		XRayTraceID:    traceID.XRay(),
+		DatadogTraceID: traceID.Datadog(),
+	}
+
+	var query strings.Builder
+	if err := l.query.Execute(&query, params); err != nil {
+		return "", fmt.Errorf("failed to render Loki query: %w", err)
+	}
+	return query.String(), nil
+}
+
+// queryRange executes a single query_range call and returns the entries sorted by timestamp
+func (l *LokiBackend) queryRange(ctx context.Context, query string, start, end time.Time) (model.Logs, error) {
+	params := url.Values{}
+	params.Set("query", query)
+	params.Set("limit", strconv.Itoa(lokiPageSize))
+	params.Set("direction", "forward")
+	if !start.IsZero() {
+		params.Set("start", strconv.FormatInt(start.UnixNano(), 10))
+	}
+	if !end.IsZero() {
+		params.Set("end", strconv.FormatInt(end.UnixNano(), 10))
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, l.baseURL+"/loki/api/v1/query_range?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Loki request: %w", err)
+	}
+	// Ask Loki to return structured metadata and parsed labels separately from stream labels
+	httpReq.Header.Set("X-Loki-Response-Encoding-Flags", "categorize-labels")
+	if l.tenantID != "" {
+		httpReq.Header.Set("X-Scope-OrgID", l.tenantID)
+	}
+	if l.username != "" {
+		httpReq.SetBasicAuth(l.username, l.password)
+	}
+	if l.bearerToken != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+l.bearerToken)
+	}
+
+	resp, err := l.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute Loki request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected Loki response status: %s", resp.Status)
+	}
+
+	var lresp lokiResponse
+	if err := json.NewDecoder(resp.Body).Decode(&lresp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if lresp.Data.ResultType != "streams" {
+		return nil, fmt.Errorf("unexpected Loki result type: %s", lresp.Data.ResultType)
+	}
+
+	var logs model.Logs
+	for _, stream := range lresp.Data.Result {
+		for _, value := range stream.Values {
+			entry, err := convertLokiEntry(stream.Stream, value)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse response: %w", err)
+			}
+			logs = append(logs, entry)
+		}
+	}
+
+	// Entries are sorted within each stream only
+	sortLogsByTimestamp(logs)
+
+	return logs, nil
+}
+
+// convertLokiEntry converts a Loki log line and its labels to a log model
+func convertLokiEntry(labels map[string]string, value []json.RawMessage) (model.Log, error) {
+	if len(value) < 2 {
+		return model.Log{}, fmt.Errorf("unexpected Loki value length: %d", len(value))
+	}
+
+	var tsStr, line string
+	if err := json.Unmarshal(value[0], &tsStr); err != nil {
+		return model.Log{}, err
+	}
+	if err := json.Unmarshal(value[1], &line); err != nil {
+		return model.Log{}, err
+	}
+	ts, err := strconv.ParseInt(tsStr, 10, 64)
+	if err != nil {
+		return model.Log{}, err
+	}
+
+	l := model.Log{
+		Timestamp:  time.Unix(0, ts),
+		Message:    line,
+		Attributes: make(map[string]any),
+	}
+
+	for k, v := range labels {
+		l.Attributes[k] = v
+	}
+
+	metadata := map[string]string{}
+	if len(value) > 2 {
+		var m lokiEntryMetadata
+		if err := json.Unmarshal(value[2], &m); err != nil {
+			return model.Log{}, err
+		}
+		maps.Copy(metadata, m.Parsed)
+		maps.Copy(metadata, m.StructuredMetadata)
+		for k, v := range metadata {
+			l.Attributes[k] = v
+		}
+	}
+
+	l.TraceID = firstLokiValue(metadata, labels, lokiTraceIDKeys)
+	l.SpanID = firstLokiValue(metadata, labels, lokiSpanIDKeys)
+
+	return l, nil
+}
+
+// lokiEntryKey returns the key deduplicating the entry across pages
+func lokiEntryKey(entry model.Log) string {
+	// Map keys are sorted by fmt, so the same entry always has the same key
+	return fmt.Sprintf("%d\x00%s\x00%v", entry.Timestamp.UnixNano(), entry.Message, entry.Attributes)
+}
+
+// firstLokiValue returns the first value found for the keys, preferring structured metadata over stream labels
+func firstLokiValue(metadata, labels map[string]string, keys []string) string {
+	for _, m := range []map[string]string{metadata, labels} {
+		for _, k := range keys {
+			if v, ok := m[k]; ok && v != "" {
+				return v
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,271 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	gconfig "github.com/ymtdzzz/telemetry-glue/pkg/app/config"
+	"github.com/ymtdzzz/telemetry-glue/pkg/app/model"
+)
+
+func TestLokiBackendBuildQuery(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		traceID string
+		want    string
+	}{
+		{
+			name:    "default query with the trace ID as given",
+			traceID: "a3ce929d0e0e4736",
+			want:    `{job=~".+"} |= "a3ce929d0e0e4736"`,
+		},
+		{
+			name:    "W3C form",
+			query:   `{app="api"} | json | trace_id="{{.TraceIDW3C}}"`,
+			traceID: "a3ce929d0e0e4736",
+			want:    `{app="api"} | json | trace_id="0000000000000000a3ce929d0e0e4736"`,
+		},
+		{
+			name:    "other forms",
+			query:   `{app="api"} |~ "{{.TraceIDHex}}|{{.XRayTraceID}}|{{.DatadogTraceID}}"`,
+			traceID: "1-5759e988-bd862e3fe1be46a994272793",
+			want:    `{app="api"} |~ "5759e988bd862e3fe1be46a994272793|1-5759e988-bd862e3fe1be46a994272793|16266516598257821587"`,
+		},
+		{
+			name:    "escaped trace ID as given",
+			traceID: `projects/a"b\c/traces/4bf92f3577b34da6a3ce929d0e0e4736`,
+			want:    `{job=~".+"} |= "projects/a\"b\\c/traces/4bf92f3577b34da6a3ce929d0e0e4736"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := NewLokiBackend(&gconfig.LokiConfig{Query: tt.query})
+			if err != nil {
+				t.Fatal(err)
+			}
+			traceID, err := model.ParseTraceID(tt.traceID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := l.buildQuery(traceID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("buildQuery() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// lokiTestEntry is a log line served by the fake Loki server
+type lokiTestEntry struct {
+	ts   int64
+	line string
+}
+
+// fakeLoki serves the entries from the start parameter in ascending order, up to the limit
+func fakeLoki(t *testing.T, entries []lokiTestEntry) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var start int64
+		if s := r.URL.Query().Get("start"); s != "" {
+			var err error
+			if start, err = strconv.ParseInt(s, 10, 64); err != nil {
+				t.Error(err)
+			}
+		}
+		limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+		if err != nil {
+			t.Error(err)
+		}
+
+		values := [][]string{}
+		for _, e := range entries {
+			if e.ts >= start && len(values) < limit {
+				values = append(values, []string{strconv.FormatInt(e.ts, 10), e.line})
+			}
+		}
+		resp := map[string]any{
+			"status": "success",
+			"data": map[string]any{
+				"resultType": "streams",
+				"result":     []any{map[string]any{"stream": map[string]string{"job": "api"}, "values": values}},
+			},
+		}
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			t.Error(err)
+		}
+	}))
+}
+
+func TestLokiBackendSearchLogsPages(t *testing.T) {
+	traceID, err := model.ParseTraceID("4bf92f3577b34da6a3ce929d0e0e4736")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// entries returns n entries with entriesPerNano entries sharing each timestamp
+	entries := func(n, entriesPerNano int, line func(i int) string) []lokiTestEntry {
+		var es []lokiTestEntry
+		for i := range n {
+			es = append(es, lokiTestEntry{ts: 1700000000000000000 + int64(i/entriesPerNano), line: line(i)})
+		}
+		return es
+	}
+	distinct := func(i int) string { return fmt.Sprintf("line %d", i) }
+
+	tests := []struct {
+		name          string
+		entries       []lokiTestEntry
+		want          int
+		wantTruncated int
+	}{
+		{
+			name:    "single page",
+			entries: entries(100, 10, distinct),
+			want:    100,
+		},
+		{
+			name:    "pages sharing the boundary timestamp",
+			entries: entries(2500, 7, distinct),
+			want:    2500,
+		},
+		{
+			name:    "identical entries are kept",
+			entries: entries(2500, 6, func(i int) string { return fmt.Sprintf("retry %d", i/2) }),
+			want:    2500,
+		},
+		{
+			name:          "whole page at the same timestamp",
+			entries:       entries(1500, 1500, distinct),
+			want:          lokiPageSize,
+			wantTruncated: lokiPageSize,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := fakeLoki(t, tt.entries)
+			defer server.Close()
+
+			l, err := NewLokiBackend(&gconfig.LokiConfig{URL: server.URL})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := l.SearchLogs(context.Background(), &SearchLogsRequest{TraceID: traceID})
+
+			var truncErr *TruncatedError
+			switch {
+			case tt.wantTruncated == 0 && err != nil:
+				t.Fatalf("SearchLogs() error = %v", err)
+			case tt.wantTruncated > 0 && (!errors.As(err, &truncErr) || truncErr.Limit != tt.wantTruncated):
+				t.Fatalf("SearchLogs() error = %v, want truncation at %d", err, tt.wantTruncated)
+			}
+
+			if len(got) != tt.want {
+				t.Fatalf("SearchLogs() returned %d logs, want %d", len(got), tt.want)
+			}
+			for i, log := range got {
+				if log.Message != tt.entries[i].line || log.Timestamp.UnixNano() != tt.entries[i].ts {
+					t.Fatalf("log %d = %s at %d, want %s at %d", i, log.Message, log.Timestamp.UnixNano(), tt.entries[i].line, tt.entries[i].ts)
+				}
+			}
+		})
+	}
+}
+
+func TestConvertLokiEntry(t *testing.T) {
+	tests := []struct {
+		name        string
+		labels      map[string]string
+		value       string
+		wantAttrs   map[string]any
+		wantTraceID string
+		wantSpanID  string
+		wantErr     bool
+	}{
+		{
+			name:        "IDs in stream labels",
+			labels:      map[string]string{"job": "api", "trace_id": "t1", "span_id": "s1"},
+			value:       `["1700000000000000000", "request done"]`,
+			wantAttrs:   map[string]any{"job": "api", "trace_id": "t1", "span_id": "s1"},
+			wantTraceID: "t1",
+			wantSpanID:  "s1",
+		},
+		{
+			name:        "IDs in structured metadata",
+			labels:      map[string]string{"job": "api"},
+			value:       `["1700000000000000000", "request done", {"structuredMetadata": {"traceID": "t1", "spanID": "s1"}}]`,
+			wantAttrs:   map[string]any{"job": "api", "traceID": "t1", "spanID": "s1"},
+			wantTraceID: "t1",
+			wantSpanID:  "s1",
+		},
+		{
+			name:        "IDs in parsed labels",
+			labels:      map[string]string{"job": "api"},
+			value:       `["1700000000000000000", "{\"trace.id\":\"t1\"}", {"parsed": {"trace.id": "t1", "level": "info"}}]`,
+			wantAttrs:   map[string]any{"job": "api", "trace.id": "t1", "level": "info"},
+			wantTraceID: "t1",
+		},
+		{
+			name:        "structured metadata preferred over parsed and stream labels",
+			labels:      map[string]string{"trace_id": "label"},
+			value:       `["1700000000000000000", "request done", {"structuredMetadata": {"trace_id": "metadata"}, "parsed": {"trace_id": "parsed"}}]`,
+			wantAttrs:   map[string]any{"trace_id": "metadata"},
+			wantTraceID: "metadata",
+		},
+		{
+			name:    "missing line",
+			value:   `["1700000000000000000"]`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid timestamp",
+			value:   `["yesterday", "request done"]`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var value []json.RawMessage
+			if err := json.Unmarshal([]byte(tt.value), &value); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := convertLokiEntry(tt.labels, value)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("convertLokiEntry() = %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Timestamp.Equal(time.Unix(0, 1700000000000000000)) {
+				t.Errorf("Timestamp = %v, want %v", got.Timestamp, time.Unix(0, 1700000000000000000))
+			}
+			if !reflect.DeepEqual(got.Attributes, tt.wantAttrs) {
+				t.Errorf("Attributes = %v, want %v", got.Attributes, tt.wantAttrs)
+			}
+			if got.TraceID != tt.wantTraceID {
+				t.Errorf("TraceID = %s, want %s", got.TraceID, tt.wantTraceID)
+			}
+			if got.SpanID != tt.wantSpanID {
+				t.Errorf("SpanID = %s, want %s", got.SpanID, tt.wantSpanID)
+			}
+		})
+	}
+}
@@ -2,10 +2,12 @@ package backend
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
 	"maps"
+	"time"
 
 	"github.com/newrelic/newrelic-client-go/v2/pkg/config"
 	"github.com/newrelic/newrelic-client-go/v2/pkg/nerdgraph"
@@ -13,10 +15,21 @@ import (
 	"github.com/ymtdzzz/telemetry-glue/pkg/app/model"
 )
 
+const (
+	// nrqlMaxLimit is the maximum number of rows NRQL returns for a single query (LIMIT MAX)
+	nrqlMaxLimit = 5000
+	// defaultNewRelicMaxSpans is the default maximum number of spans fetched for a trace
+	defaultNewRelicMaxSpans = 20000
+	// defaultNewRelicMaxLogs is the default maximum number of logs fetched for a trace
+	defaultNewRelicMaxLogs = 20000
+)
+
 // NewRelicBackend represents a NewRelic backend
 type NewRelicBackend struct {
 	client    *nerdgraph.NerdGraph
 	accountID int
+	maxSpans  int
+	maxLogs   int
 }
 
 // NewNewRelicBackend creates a new NewRelic backend
@@ -25,25 +38,173 @@ func NewNewRelicBackend(cfg *gconfig.NewRelicConfig) *NewRelicBackend {
 	nrcfg.PersonalAPIKey = cfg.APIKey
 	client := nerdgraph.New(nrcfg)
 
+	maxSpans := cfg.MaxSpans
+	if maxSpans <= 0 {
+		maxSpans = defaultNewRelicMaxSpans
+	}
+	maxLogs := cfg.MaxLogs
+	if maxLogs <= 0 {
+		maxLogs = defaultNewRelicMaxLogs
+	}
+
 	return &NewRelicBackend{
 		client:    &client,
 		accountID: cfg.AccountID,
+		maxSpans:  maxSpans,
+		maxLogs:   maxLogs,
 	}
 }
 
+// SearchSpans fetches all spans of the trace.
+// A TruncatedError is returned along with the spans when the trace has more spans than maxSpans.
 func (n *NewRelicBackend) SearchSpans(ctx context.Context, req *SearchSpansRequest) (model.Spans, error) {
-	// Build NRQL query to get all spans for the trace
-	nrqlQuery := fmt.Sprintf(`
-		SELECT * 
-		FROM Span 
-		WHERE trace.id = '%s' 
-		SINCE %d UNTIL %d 
-		ORDER BY timestamp ASC`,
-		req.TraceID,
-		req.TimeRange.Start.UnixMilli(),
-		req.TimeRange.End.UnixMilli(),
+	rows, err := fetchNRQLPages(ctx, n.executeNRQL, "Span", "id", req.TraceID, req.TimeRange, n.maxSpans)
+
+	var spans model.Spans
+	for _, row := range rows {
+		spans = append(spans, convertNRSpan(row))
+	}
+
+	return spans, err
+}
+
+// LocateTrace discovers the time range of the trace with a cheap aggregate query over the lookback window
+func (n *NewRelicBackend) LocateTrace(ctx context.Context, req *LocateTraceRequest) (*TimeRange, error) {
+	now := time.Now()
+	nrqlQuery, err := newNRQLBuilder("min(timestamp), max(timestamp), max(duration.ms)", "Span").
+		WhereTraceID("trace.id", req.TraceID).
+		Between(now.Add(-req.Lookback), now).
+		Build()
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := n.executeNRQL(ctx, nrqlQuery)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, ErrTraceNotFound
+	}
+
+	minTS, ok := results[0]["min.timestamp"].(float64)
+	if !ok || minTS == 0 {
+		return nil, ErrTraceNotFound
+	}
+	maxTS, _ := results[0]["max.timestamp"].(float64)
+	maxDuration, _ := results[0]["max.duration.ms"].(float64)
+
+	// The latest span may still be running after its start, so add the longest span duration
+	return &TimeRange{
+		Start: time.UnixMilli(int64(minTS)).Add(-traceTimeRangePadding),
+		End:   time.UnixMilli(int64(maxTS + maxDuration)).Add(traceTimeRangePadding),
+	}, nil
+}
+
+// SearchLogs fetches all logs of the trace.
+// A TruncatedError is returned along with the logs when the trace has more logs than maxLogs.
+func (n *NewRelicBackend) SearchLogs(ctx context.Context, req *SearchLogsRequest) (model.Logs, error) {
+	rows, err := fetchNRQLPages(ctx, n.executeNRQL, "Log", "messageId", req.TraceID, req.TimeRange, n.maxLogs)
+
+	var logs model.Logs
+	for _, row := range rows {
+		logs = append(logs, convertNRLog(row))
+	}
+
+	return logs, err
+}
+
+// fetchNRQLPages fetches all rows of the event type for the trace. As a single NRQL query returns at most
+// 5000 rows, the rows are fetched in pages by moving the SINCE cursor to the timestamp of the last row.
+// The rows at the cursor timestamp are returned again in the next page, so they are deduplicated by idKey.
+// A TruncatedError is returned along with the rows when there are more rows than limit.
+func fetchNRQLPages(
+	ctx context.Context,
+	execute func(context.Context, string) ([]map[string]any, error),
+	eventType string,
+	idKey string,
+	traceID model.TraceID,
+	timeRange *TimeRange,
+	limit int,
+) ([]map[string]any, error) {
+	// NRQL queries cannot span unlimited time, so the paging needs a start time
+	if timeRange == nil {
+		return nil, errors.New("the time range of the trace is required to query New Relic")
+	}
+
+	var (
+		rows  []map[string]any
+		seen  = map[string]int{}
+		since = timeRange.Start.UnixMilli()
 	)
 
+	for {
+		nrqlQuery, err := newNRQLBuilder("*", eventType).
+			WhereTraceID("trace.id", traceID).
+			Between(time.UnixMilli(since), timeRange.End).
+			OrderBy("timestamp ASC").
+			LimitMax().
+			Build()
+		if err != nil {
+			return nil, err
+		}
+
+		results, err := execute(ctx, nrqlQuery)
+		if err != nil {
+			return nil, err
+		}
+
+		added := 0
+		occurrences := map[string]int{}
+		for _, result := range results {
+			key, err := nrRowKey(result, idKey)
+			if err != nil {
+				return nil, err
+			}
+			// Identical rows without an ID may legitimately occur several times, so only
+			// as many occurrences as were fetched by the previous pages are skipped
+			occurrences[key]++
+			if occurrences[key] <= seen[key] {
+				continue
+			}
+			if len(rows) >= limit {
+				return rows, &TruncatedError{Limit: limit}
+			}
+			seen[key]++
+
+			rows = append(rows, result)
+			added++
+		}
+
+		if len(results) < nrqlMaxLimit {
+			return rows, nil
+		}
+
+		// The cursor cannot move forward when a whole page shares the same timestamp
+		last, ok := results[len(results)-1]["timestamp"].(float64)
+		if !ok || added == 0 {
+			return rows, &TruncatedError{Limit: len(rows)}
+		}
+		since = int64(last)
+	}
+}
+
+// nrRowKey returns the key deduplicating the row, which is the ID when the row has one.
+// Rows without an ID are keyed by their content so that distinct rows are not collapsed into one.
+func nrRowKey(row map[string]any, idKey string) (string, error) {
+	if id, ok := row[idKey]; ok && id != nil && id != "" {
+		return fmt.Sprintf("%s:%v", idKey, id), nil
+	}
+	// Map keys are sorted by json.Marshal, so the same row always has the same key
+	b, err := json.Marshal(row)
+	if err != nil {
+		return "", fmt.Errorf("failed to build the row key: %w", err)
+	}
+	return string(b), nil
+}
+
+// executeNRQL executes the NRQL query via NerdGraph and returns the result rows
+func (n *NewRelicBackend) executeNRQL(ctx context.Context, nrqlQuery string) ([]map[string]any, error) {
 	log.Printf("Executing NRQL query: %s", nrqlQuery)
 
 	// Build GraphQL query
@@ -64,22 +225,22 @@ func (n *NewRelicBackend) SearchSpans(ctx context.Context, req *SearchSpansReque
 	}
 
 	// Execute the query
-	resp, err := n.client.Query(graphqlQuery, variables)
+	resp, err := n.client.QueryWithContext(ctx, graphqlQuery, variables)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute NerdGraph query: %w", err)
 	}
 
 	// Parse the response
-	spans, err := n.parseSpansResponse(resp)
+	results, err := n.parseNRQLResponse(resp)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse response: %w", err)
 	}
 
-	return spans, nil
+	return results, nil
 }
 
-// parseSpansResponse parses the NerdGraph response for SearchSpans
-func (n *NewRelicBackend) parseSpansResponse(resp any) (model.Spans, error) {
+// parseNRQLResponse parses the NerdGraph response for NRQL queries
+func (n *NewRelicBackend) parseNRQLResponse(resp any) ([]map[string]any, error) {
 	// First, assert the response as QueryResponse type
 	queryResp, ok := resp.(nerdgraph.QueryResponse)
 	if !ok {
@@ -107,23 +268,66 @@ func (n *NewRelicBackend) parseSpansResponse(resp any) (model.Spans, error) {
 		return nil, fmt.Errorf("results not found in response")
 	}
 
-	var spans model.Spans
+	var rows []map[string]any
 
 	for _, result := range results {
 		resultMap, ok := result.(map[string]any)
 		if !ok {
 			continue
 		}
+		rows = append(rows, resultMap)
+	}
+
+	return rows, nil
+}
 
-		span := make(model.Span)
-		maps.Copy(span, resultMap)
+// convertNRSpan converts a row of the Span event type to a span model
+func convertNRSpan(row map[string]any) model.Span {
+	attrs := maps.Clone(row)
 
-		spans = append(spans, span)
+	span := model.Span{
+		TraceID:       takeString(attrs, "trace.id"),
+		SpanID:        takeString(attrs, "id"),
+		ParentSpanID:  takeString(attrs, "parent.id"),
+		ServiceName:   takeString(attrs, "service.name", "entity.name", "appName"),
+		Name:          takeString(attrs, "name"),
+		Kind:          model.ParseSpanKind(takeString(attrs, "span.kind")),
+		Status:        model.ParseSpanStatus(takeString(attrs, "otel.status_code")),
+		StatusMessage: takeString(attrs, "otel.status_description"),
+	}
+	if ts, ok := takeFloat(attrs, "timestamp"); ok {
+		span.StartTime = milliTime(ts)
 	}
+	if d, ok := takeFloat(attrs, "duration.ms"); ok {
+		span.Duration = milliDuration(d)
+	}
+	span.Attributes = attrs
 
-	return spans, nil
+	return span
 }
 
-func (n *NewRelicBackend) SearchLogs(ctx context.Context, req *SearchLogsRequest) (model.Logs, error) {
-	return nil, errors.New("not implemented")
+// convertNRLog converts a row of the Log event type to a log model
+func convertNRLog(row map[string]any) model.Log {
+	l := model.Log{
+		Attributes: make(map[string]any),
+	}
+
+	for k, v := range row {
+		switch k {
+		case "message":
+			l.Message = stringValue(v)
+		case "timestamp":
+			if ts, ok := v.(float64); ok {
+				l.Timestamp = time.UnixMilli(int64(ts))
+			}
+		case "trace.id":
+			l.TraceID = stringValue(v)
+		case "span.id":
+			l.SpanID = stringValue(v)
+		default:
+			l.Attributes[k] = v
+		}
+	}
+
+	return l
 }
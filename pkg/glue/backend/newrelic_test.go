@@ -0,0 +1,189 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ymtdzzz/telemetry-glue/pkg/app/model"
+)
+
+func TestConvertNRLog(t *testing.T) {
+	tests := []struct {
+		name string
+		row  map[string]any
+		want model.Log
+	}{
+		{
+			name: "all fields",
+			row: map[string]any{
+				"message":   "connection refused",
+				"timestamp": float64(1700000000123),
+				"trace.id":  "4bf92f3577b34da6a3ce929d0e0e4736",
+				"span.id":   "00f067aa0ba902b7",
+				"level":     "ERROR",
+			},
+			want: model.Log{
+				Timestamp:  time.UnixMilli(1700000000123),
+				TraceID:    "4bf92f3577b34da6a3ce929d0e0e4736",
+				SpanID:     "00f067aa0ba902b7",
+				Message:    "connection refused",
+				Attributes: map[string]any{"level": "ERROR"},
+			},
+		},
+		{
+			name: "null fields",
+			row: map[string]any{
+				"message":  nil,
+				"trace.id": "4bf92f3577b34da6a3ce929d0e0e4736",
+				"span.id":  nil,
+			},
+			want: model.Log{
+				TraceID:    "4bf92f3577b34da6a3ce929d0e0e4736",
+				Attributes: map[string]any{},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := convertNRLog(tt.row)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("convertNRLog() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// nrqlSincePattern extracts the SINCE cursor from the queries built by fetchNRQLPages
+var nrqlSincePattern = regexp.MustCompile(`SINCE (\d+)`)
+
+// fakeNRQL returns the rows at or after the SINCE cursor, at most nrqlMaxLimit of them,
+// as NRQL does for the paging queries. The rows must be sorted by timestamp.
+func fakeNRQL(rows []map[string]any) func(context.Context, string) ([]map[string]any, error) {
+	return func(_ context.Context, query string) ([]map[string]any, error) {
+		m := nrqlSincePattern.FindStringSubmatch(query)
+		if m == nil {
+			return nil, fmt.Errorf("no SINCE clause in %s", query)
+		}
+		since, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		var results []map[string]any
+		for _, row := range rows {
+			if int64(row["timestamp"].(float64)) >= since && len(results) < nrqlMaxLimit {
+				results = append(results, row)
+			}
+		}
+		return results, nil
+	}
+}
+
+func TestFetchNRQLPages(t *testing.T) {
+	traceID, err := model.ParseTraceID("4bf92f3577b34da6a3ce929d0e0e4736")
+	if err != nil {
+		t.Fatal(err)
+	}
+	timeRange := &TimeRange{Start: time.UnixMilli(1700000000000), End: time.UnixMilli(1700000100000)}
+
+	// rows returns n rows with rowsPerMilli rows sharing each timestamp
+	rows := func(n, rowsPerMilli int, row func(i int) map[string]any) []map[string]any {
+		var rs []map[string]any
+		for i := range n {
+			r := row(i)
+			r["timestamp"] = float64(1700000000000 + i/rowsPerMilli)
+			rs = append(rs, r)
+		}
+		return rs
+	}
+	withID := func(i int) map[string]any { return map[string]any{"id": fmt.Sprintf("span-%d", i)} }
+
+	tests := []struct {
+		name          string
+		rows          []map[string]any
+		limit         int
+		want          int
+		wantTruncated int
+	}{
+		{
+			name:  "single page",
+			rows:  rows(100, 10, withID),
+			limit: 20000,
+			want:  100,
+		},
+		{
+			name:  "pages deduplicated by ID",
+			rows:  rows(12000, 7, withID),
+			limit: 20000,
+			want:  12000,
+		},
+		{
+			name:  "rows without ID are not collapsed",
+			rows:  rows(12000, 7, func(i int) map[string]any { return map[string]any{"id": nil, "name": fmt.Sprintf("span-%d", i)} }),
+			limit: 20000,
+			want:  12000,
+		},
+		{
+			name:  "identical rows without ID are kept",
+			rows:  rows(12000, 8, func(i int) map[string]any { return map[string]any{"message": fmt.Sprintf("retry %d", i/2)} }),
+			limit: 20000,
+			want:  12000,
+		},
+		{
+			name:          "limit",
+			rows:          rows(12000, 7, withID),
+			limit:         7000,
+			want:          7000,
+			wantTruncated: 7000,
+		},
+		{
+			name:          "whole page at the same timestamp",
+			rows:          rows(6000, 6000, withID),
+			limit:         20000,
+			want:          nrqlMaxLimit,
+			wantTruncated: nrqlMaxLimit,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := fetchNRQLPages(context.Background(), fakeNRQL(tt.rows), "Span", "id", traceID, timeRange, tt.limit)
+
+			var truncErr *TruncatedError
+			switch {
+			case tt.wantTruncated == 0 && err != nil:
+				t.Fatalf("fetchNRQLPages() error = %v", err)
+			case tt.wantTruncated > 0 && (!errors.As(err, &truncErr) || truncErr.Limit != tt.wantTruncated):
+				t.Fatalf("fetchNRQLPages() error = %v, want truncation at %d", err, tt.wantTruncated)
+			}
+
+			if len(got) != tt.want {
+				t.Fatalf("fetchNRQLPages() returned %d rows, want %d", len(got), tt.want)
+			}
+			for i, row := range got {
+				if !reflect.DeepEqual(row, tt.rows[i]) {
+					t.Fatalf("row %d = %v, want %v", i, row, tt.rows[i])
+				}
+			}
+		})
+	}
+}
+
+func TestFetchNRQLPagesWithoutTimeRange(t *testing.T) {
+	traceID, err := model.ParseTraceID("4bf92f3577b34da6a3ce929d0e0e4736")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := fetchNRQLPages(context.Background(), fakeNRQL(nil), "Span", "id", traceID, nil, 20000)
+	if err == nil {
+		t.Fatalf("fetchNRQLPages() = %v, want an error", rows)
+	}
+}
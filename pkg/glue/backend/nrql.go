@@ -0,0 +1,111 @@
+package backend
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ymtdzzz/telemetry-glue/pkg/app/model"
+)
+
+// nrqlTraceIDPattern matches the trace ID formats stored by New Relic:
+// 32-hex W3C trace IDs and 16-hex 64-bit trace IDs
+var nrqlTraceIDPattern = regexp.MustCompile(`^(?:[0-9a-fA-F]{32}|[0-9a-fA-F]{16})$`)
+
+// nrqlBuilder builds NRQL queries from trusted clauses and untrusted values.
+// Values given by users (e.g., trace IDs from CLI args or Slack) are validated and
+// escaped so that they cannot change the structure of the query.
+type nrqlBuilder struct {
+	selectClause string
+	from         string
+	where        []string
+	since        int64
+	until        int64
+	orderBy      string
+	limitMax     bool
+	err          error
+}
+
+// newNRQLBuilder starts a query selecting the expression from the event type.
+// Both arguments must be constants defined in code.
+func newNRQLBuilder(selectClause, from string) *nrqlBuilder {
+	return &nrqlBuilder{
+		selectClause: selectClause,
+		from:         from,
+	}
+}
+
+// WhereTraceID adds a condition matching the trace ID after validating its format.
+// 64-bit trace IDs are matched in both the 16-hex and the zero-padded 32-hex forms
+// as New Relic stores the ID as reported by the agent.
+func (b *nrqlBuilder) WhereTraceID(attribute string, traceID model.TraceID) *nrqlBuilder {
+	ids := []string{traceID.W3C()}
+	if traceID.Is64Bit() {
+		ids = append(ids, traceID.Hex())
+	}
+
+	literals := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if !nrqlTraceIDPattern.MatchString(id) {
+			b.err = fmt.Errorf("invalid trace ID %q: must be 16 or 32 hex characters", id)
+			return b
+		}
+		literals = append(literals, nrqlString(id))
+	}
+
+	if len(literals) == 1 {
+		b.where = append(b.where, fmt.Sprintf("%s = %s", attribute, literals[0]))
+	} else {
+		b.where = append(b.where, fmt.Sprintf("%s IN (%s)", attribute, strings.Join(literals, ", ")))
+	}
+	return b
+}
+
+// Between sets the SINCE and UNTIL clauses
+func (b *nrqlBuilder) Between(start, end time.Time) *nrqlBuilder {
+	b.since = start.UnixMilli()
+	b.until = end.UnixMilli()
+	return b
+}
+
+// OrderBy sets the ORDER BY clause. The expression must be a constant defined in code.
+func (b *nrqlBuilder) OrderBy(expression string) *nrqlBuilder {
+	b.orderBy = expression
+	return b
+}
+
+// LimitMax sets LIMIT MAX to fetch the maximum number of rows NRQL allows
+func (b *nrqlBuilder) LimitMax() *nrqlBuilder {
+	b.limitMax = true
+	return b
+}
+
+// Build returns the NRQL query or the first validation error
+func (b *nrqlBuilder) Build() (string, error) {
+	if b.err != nil {
+		return "", b.err
+	}
+
+	var q strings.Builder
+	fmt.Fprintf(&q, "SELECT %s FROM %s", b.selectClause, b.from)
+	if len(b.where) > 0 {
+		fmt.Fprintf(&q, " WHERE %s", strings.Join(b.where, " AND "))
+	}
+	if b.since != 0 || b.until != 0 {
+		fmt.Fprintf(&q, " SINCE %d UNTIL %d", b.since, b.until)
+	}
+	if b.orderBy != "" {
+		fmt.Fprintf(&q, " ORDER BY %s", b.orderBy)
+	}
+	if b.limitMax {
+		q.WriteString(" LIMIT MAX")
+	}
+
+	return q.String(), nil
+}
+
+// nrqlString quotes the value as an NRQL string literal, escaping backslashes and single quotes
+func nrqlString(value string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
+}
@@ -0,0 +1,77 @@
+package backend
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ymtdzzz/telemetry-glue/pkg/app/model"
+)
+
+func TestNRQLBuilder(t *testing.T) {
+	w3c, err := model.ParseTraceID("4bf92f3577b34da6a3ce929d0e0e4736")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bit64, err := model.ParseTraceID("a3ce929d0e0e4736")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start, end := time.UnixMilli(1700000000000), time.UnixMilli(1700000060000)
+
+	tests := []struct {
+		name    string
+		builder *nrqlBuilder
+		want    string
+	}{
+		{
+			name:    "select only",
+			builder: newNRQLBuilder("count(*)", "Span"),
+			want:    "SELECT count(*) FROM Span",
+		},
+		{
+			name: "W3C trace ID",
+			builder: newNRQLBuilder("*", "Span").
+				WhereTraceID("trace.id", w3c).
+				Between(start, end).
+				OrderBy("timestamp ASC").
+				LimitMax(),
+			want: "SELECT * FROM Span WHERE trace.id = '4bf92f3577b34da6a3ce929d0e0e4736' SINCE 1700000000000 UNTIL 1700000060000 ORDER BY timestamp ASC LIMIT MAX",
+		},
+		{
+			name:    "64-bit trace ID in both forms",
+			builder: newNRQLBuilder("*", "Log").WhereTraceID("trace.id", bit64),
+			want:    "SELECT * FROM Log WHERE trace.id IN ('0000000000000000a3ce929d0e0e4736', 'a3ce929d0e0e4736')",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.builder.Build()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Build() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNRQLString(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "abc", want: `'abc'`},
+		{value: `it's`, want: `'it\'s'`},
+		{value: `a\' OR 1=1`, want: `'a\\\' OR 1=1'`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if got := nrqlString(tt.value); got != tt.want {
+				t.Errorf("nrqlString() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
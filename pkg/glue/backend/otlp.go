@@ -0,0 +1,234 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ymtdzzz/telemetry-glue/pkg/app/model"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// convertOTLPResourceSpans flattens OTLP resource spans into span models.
+// Resource, scope and span attributes are merged into the span attributes.
+func convertOTLPResourceSpans(resourceSpans []*tracepb.ResourceSpans) model.Spans {
+	var spans model.Spans
+
+	for _, rs := range resourceSpans {
+		resourceAttrs := otlpAttributes(rs.GetResource().GetAttributes())
+
+		for _, ss := range rs.GetScopeSpans() {
+			scope := ss.GetScope()
+
+			for _, s := range ss.GetSpans() {
+				attrs := make(map[string]any)
+				for k, v := range resourceAttrs {
+					attrs[k] = v
+				}
+				if scope.GetName() != "" {
+					attrs["otel.library.name"] = scope.GetName()
+				}
+				if scope.GetVersion() != "" {
+					attrs["otel.library.version"] = scope.GetVersion()
+				}
+				for k, v := range otlpAttributes(scope.GetAttributes()) {
+					attrs["otel.scope."+k] = v
+				}
+				for k, v := range otlpAttributes(s.GetAttributes()) {
+					attrs[k] = v
+				}
+
+				span := model.Span{
+					TraceID:       hex.EncodeToString(s.GetTraceId()),
+					SpanID:        hex.EncodeToString(s.GetSpanId()),
+					ParentSpanID:  hex.EncodeToString(s.GetParentSpanId()),
+					ServiceName:   takeString(attrs, "service.name"),
+					Name:          s.GetName(),
+					Kind:          model.ParseSpanKind(s.GetKind().String()),
+					StartTime:     time.Unix(0, int64(s.GetStartTimeUnixNano())),
+					Duration:      otlpDuration(s.GetStartTimeUnixNano(), s.GetEndTimeUnixNano()),
+					Status:        model.ParseSpanStatus(s.GetStatus().GetCode().String()),
+					StatusMessage: s.GetStatus().GetMessage(),
+				}
+
+				var events []map[string]any
+				for _, e := range s.GetEvents() {
+					event := otlpAttributes(e.GetAttributes())
+					event["name"] = e.GetName()
+					event["timestamp"] = time.Unix(0, int64(e.GetTimeUnixNano())).Format(time.RFC3339Nano)
+					events = append(events, event)
+				}
+				if len(events) > 0 {
+					attrs["events"] = events
+				}
+
+				var links []string
+				for _, l := range s.GetLinks() {
+					links = append(links, hex.EncodeToString(l.GetTraceId())+":"+hex.EncodeToString(l.GetSpanId()))
+				}
+				if len(links) > 0 {
+					attrs["links"] = links
+				}
+
+				span.Attributes = attrs
+				spans = append(spans, span)
+			}
+		}
+	}
+
+	return spans
+}
+
+// otlpDuration returns the span duration, or zero for a span that ended before it started
+// (e.g., unfinished spans with no end time) as the unsigned subtraction would wrap around
+func otlpDuration(startUnixNano, endUnixNano uint64) time.Duration {
+	if endUnixNano < startUnixNano {
+		return 0
+	}
+	return time.Duration(endUnixNano - startUnixNano)
+}
+
+// convertOTLPResourceLogs flattens OTLP resource logs into log models.
+// Resource and log attributes are merged into the log attributes.
+func convertOTLPResourceLogs(resourceLogs []*logspb.ResourceLogs) model.Logs {
+	var logs model.Logs
+
+	for _, rl := range resourceLogs {
+		resourceAttrs := otlpAttributes(rl.GetResource().GetAttributes())
+
+		for _, sl := range rl.GetScopeLogs() {
+			for _, r := range sl.GetLogRecords() {
+				l := model.Log{
+					TraceID:    hex.EncodeToString(r.GetTraceId()),
+					SpanID:     hex.EncodeToString(r.GetSpanId()),
+					Attributes: make(map[string]any),
+				}
+
+				ts := r.GetTimeUnixNano()
+				if ts == 0 {
+					ts = r.GetObservedTimeUnixNano()
+				}
+				l.Timestamp = time.Unix(0, int64(ts))
+
+				switch body := otlpValue(r.GetBody()).(type) {
+				case nil:
+				case string:
+					l.Message = body
+				default:
+					l.Message = fmt.Sprintf("%v", body)
+				}
+
+				for k, v := range resourceAttrs {
+					l.Attributes[k] = v
+				}
+				for k, v := range otlpAttributes(r.GetAttributes()) {
+					l.Attributes[k] = v
+				}
+				if r.GetSeverityText() != "" {
+					l.Attributes["severity"] = r.GetSeverityText()
+				} else if r.GetSeverityNumber() != logspb.SeverityNumber_SEVERITY_NUMBER_UNSPECIFIED {
+					l.Attributes["severity"] = otlpSeverity(r.GetSeverityNumber())
+				}
+
+				logs = append(logs, l)
+			}
+		}
+	}
+
+	return logs
+}
+
+// otlpSeverity converts the OTLP severity number to its level name (e.g., "ERROR")
+func otlpSeverity(severity logspb.SeverityNumber) string {
+	name := strings.TrimPrefix(severity.String(), "SEVERITY_NUMBER_")
+	// Strip the sub-level suffix such as ERROR2, ERROR3 and ERROR4
+	return strings.TrimRight(name, "234")
+}
+
+// otlpAttributes converts OTLP key-values to a map
+func otlpAttributes(kvs []*commonpb.KeyValue) map[string]any {
+	attrs := make(map[string]any, len(kvs))
+	for _, kv := range kvs {
+		attrs[kv.GetKey()] = otlpValue(kv.GetValue())
+	}
+	return attrs
+}
+
+// otlpValue converts an OTLP any value to its Go representation
+func otlpValue(v *commonpb.AnyValue) any {
+	switch val := v.GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		return val.StringValue
+	case *commonpb.AnyValue_BoolValue:
+		return val.BoolValue
+	case *commonpb.AnyValue_IntValue:
+		return val.IntValue
+	case *commonpb.AnyValue_DoubleValue:
+		return val.DoubleValue
+	case *commonpb.AnyValue_BytesValue:
+		return hex.EncodeToString(val.BytesValue)
+	case *commonpb.AnyValue_ArrayValue:
+		values := make([]any, 0, len(val.ArrayValue.GetValues()))
+		for _, item := range val.ArrayValue.GetValues() {
+			values = append(values, otlpValue(item))
+		}
+		return values
+	case *commonpb.AnyValue_KvlistValue:
+		return otlpAttributes(val.KvlistValue.GetValues())
+	}
+	return nil
+}
+
+// otlpIDKeys are the JSON keys of OTLP trace and span IDs
+var otlpIDKeys = map[string]bool{
+	"traceId":        true,
+	"spanId":         true,
+	"parentSpanId":   true,
+	"trace_id":       true,
+	"span_id":        true,
+	"parent_span_id": true,
+}
+
+// normalizeOTLPJSON rewrites hex-encoded trace and span IDs into base64 so that the document
+// can be decoded by protojson. The OTLP JSON encoding uses hex IDs while protojson expects
+// base64 for bytes fields.
+func normalizeOTLPJSON(raw []byte) ([]byte, error) {
+	// Keep numbers as is to avoid losing the precision of nanosecond timestamps
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+
+	var doc any
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+	return json.Marshal(normalizeOTLPIDs(doc))
+}
+
+func normalizeOTLPIDs(v any) any {
+	switch val := v.(type) {
+	case map[string]any:
+		for k, child := range val {
+			if id, ok := child.(string); ok && otlpIDKeys[k] {
+				// Trace IDs are 16 bytes and span IDs are 8 bytes
+				if len(id) == 32 || len(id) == 16 {
+					if b, err := hex.DecodeString(id); err == nil {
+						val[k] = base64.StdEncoding.EncodeToString(b)
+					}
+				}
+				continue
+			}
+			val[k] = normalizeOTLPIDs(child)
+		}
+	case []any:
+		for i, child := range val {
+			val[i] = normalizeOTLPIDs(child)
+		}
+	}
+	return v
+}
@@ -0,0 +1,28 @@
+package backend
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOTLPDuration(t *testing.T) {
+	tests := []struct {
+		name  string
+		start uint64
+		end   uint64
+		want  time.Duration
+	}{
+		{name: "finished span", start: 1_000_000_000, end: 1_250_000_000, want: 250 * time.Millisecond},
+		{name: "zero duration", start: 1_000_000_000, end: 1_000_000_000, want: 0},
+		{name: "no end time", start: 1_000_000_000, end: 0, want: 0},
+		{name: "end before start", start: 2_000_000_000, end: 1_000_000_000, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := otlpDuration(tt.start, tt.end); got != tt.want {
+				t.Errorf("otlpDuration() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
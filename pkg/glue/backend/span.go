@@ -0,0 +1,37 @@
+package backend
+
+import (
+	"fmt"
+	"time"
+)
+
+// takeString removes the first key present in the attributes and returns its value as a string.
+// It is used to move backend-specific fields into the normalized span fields.
+func takeString(attrs map[string]any, keys ...string) string {
+	for _, k := range keys {
+		if v, ok := attrs[k]; ok && v != nil {
+			delete(attrs, k)
+			return fmt.Sprintf("%v", v)
+		}
+	}
+	return ""
+}
+
+// takeFloat removes the key from the attributes and returns its value when it is a number
+func takeFloat(attrs map[string]any, key string) (float64, bool) {
+	v, ok := attrs[key].(float64)
+	if ok {
+		delete(attrs, key)
+	}
+	return v, ok
+}
+
+// milliTime converts fractional Unix milliseconds to time
+func milliTime(ms float64) time.Time {
+	return time.UnixMicro(int64(ms * 1000))
+}
+
+// milliDuration converts fractional milliseconds to a duration
+func milliDuration(ms float64) time.Duration {
+	return time.Duration(ms * float64(time.Millisecond))
+}
@@ -0,0 +1,145 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	gconfig "github.com/ymtdzzz/telemetry-glue/pkg/app/config"
+	"github.com/ymtdzzz/telemetry-glue/pkg/app/model"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// TempoBackend represents a Grafana Tempo backend
+type TempoBackend struct {
+	client   *http.Client
+	baseURL  string
+	tenantID string
+	located  locatedSpans
+}
+
+// NewTempoBackend creates a new Tempo backend
+func NewTempoBackend(cfg *gconfig.TempoConfig) *TempoBackend {
+	return &TempoBackend{
+		client:   &http.Client{Timeout: 30 * time.Second},
+		baseURL:  strings.TrimRight(cfg.URL, "/"),
+		tenantID: cfg.TenantID,
+	}
+}
+
+func (t *TempoBackend) SearchSpans(ctx context.Context, req *SearchSpansRequest) (model.Spans, error) {
+	if spans, ok := t.located.load(req); ok {
+		return spans, nil
+	}
+	return t.fetchSpans(ctx, req)
+}
+
+// fetchSpans downloads the trace from Tempo
+func (t *TempoBackend) fetchSpans(ctx context.Context, req *SearchSpansRequest) (model.Spans, error) {
+	endpoint := fmt.Sprintf("%s/api/traces/%s", t.baseURL, req.TraceID.W3C())
+	if req.TimeRange != nil {
+		// Tempo takes the range in seconds, so the end is rounded up to keep the last spans in range
+		query := url.Values{}
+		query.Set("start", strconv.FormatInt(req.TimeRange.Start.Unix(), 10))
+		query.Set("end", strconv.FormatInt(req.TimeRange.End.Add(time.Second-1).Unix(), 10))
+		endpoint += "?" + query.Encode()
+	}
+
+	log.Printf("Executing Tempo query: %s", endpoint)
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Tempo request: %w", err)
+	}
+	httpReq.Header.Set("Accept", "application/protobuf")
+	if t.tenantID != "" {
+		httpReq.Header.Set("X-Scope-OrgID", t.tenantID)
+	}
+
+	resp, err := t.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute Tempo request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Tempo returns 404 when the trace is not found
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected Tempo response status: %s", resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Tempo response: %w", err)
+	}
+
+	resourceSpans, err := parseTempoTrace(resp.Header.Get("Content-Type"), body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return convertOTLPResourceSpans(resourceSpans), nil
+}
+
+// LocateTrace discovers the time range of the trace by fetching it within the lookback window.
+// The fetched spans are kept and returned by the following SearchSpans for the trace.
+func (t *TempoBackend) LocateTrace(ctx context.Context, req *LocateTraceRequest) (*TimeRange, error) {
+	return t.located.locate(ctx, req, t.fetchSpans)
+}
+
+func (t *TempoBackend) SearchLogs(ctx context.Context, req *SearchLogsRequest) (model.Logs, error) {
+	return nil, errors.New("not implemented")
+}
+
+// parseTempoTrace parses the trace returned by Tempo in either protobuf or OTLP JSON format
+func parseTempoTrace(contentType string, body []byte) ([]*tracepb.ResourceSpans, error) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+
+	// Tempo's Trace message shares the field number of TracesData.resource_spans,
+	// so the protobuf payload can be decoded as OTLP TracesData
+	if mediaType == "application/protobuf" || mediaType == "application/x-protobuf" {
+		var data tracepb.TracesData
+		if err := proto.Unmarshal(body, &data); err != nil {
+			return nil, err
+		}
+		return data.GetResourceSpans(), nil
+	}
+
+	// The JSON payload holds resource spans under "batches" (Tempo) or "resourceSpans" (OTLP)
+	var trace struct {
+		Batches       []json.RawMessage `json:"batches"`
+		ResourceSpans []json.RawMessage `json:"resourceSpans"`
+	}
+	if err := json.Unmarshal(body, &trace); err != nil {
+		return nil, err
+	}
+
+	var resourceSpans []*tracepb.ResourceSpans
+	for _, raw := range append(trace.Batches, trace.ResourceSpans...) {
+		normalized, err := normalizeOTLPJSON(raw)
+		if err != nil {
+			return nil, err
+		}
+
+		rs := &tracepb.ResourceSpans{}
+		if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(normalized, rs); err != nil {
+			return nil, err
+		}
+		resourceSpans = append(resourceSpans, rs)
+	}
+
+	return resourceSpans, nil
+}
@@ -0,0 +1,198 @@
+package backend
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	gconfig "github.com/ymtdzzz/telemetry-glue/pkg/app/config"
+	"github.com/ymtdzzz/telemetry-glue/pkg/app/model"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// tempoJSONResourceSpans is a resource span of the test trace in OTLP JSON with hex IDs
+const tempoJSONResourceSpans = `[{"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"frontend"}}]},"scopeSpans":[{"spans":[{"traceId":"4bf92f3577b34da6a3ce929d0e0e4736","spanId":"a3ce929d0e0e4736","name":"GET /orders","startTimeUnixNano":"1700000000000000000","endTimeUnixNano":"1700000000250000000"}]}]}]`
+
+// tempoProtoTrace returns the test trace encoded in protobuf
+func tempoProtoTrace(t *testing.T) []byte {
+	t.Helper()
+	traceID, _ := hex.DecodeString("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := hex.DecodeString("a3ce929d0e0e4736")
+	data := &tracepb.TracesData{
+		ResourceSpans: []*tracepb.ResourceSpans{{
+			Resource: &resourcepb.Resource{Attributes: []*commonpb.KeyValue{
+				{Key: "service.name", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "frontend"}}},
+			}},
+			ScopeSpans: []*tracepb.ScopeSpans{{
+				Spans: []*tracepb.Span{{
+					TraceId:           traceID,
+					SpanId:            spanID,
+					Name:              "GET /orders",
+					StartTimeUnixNano: 1700000000000000000,
+					EndTimeUnixNano:   1700000000250000000,
+				}},
+			}},
+		}},
+	}
+	body, err := proto.Marshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+func TestTempoBackendSearchSpansTimeRange(t *testing.T) {
+	traceID, err := model.ParseTraceID("4bf92f3577b34da6a3ce929d0e0e4736")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		timeRange *TimeRange
+		wantQuery string
+	}{
+		{
+			name:      "without time range",
+			wantQuery: "",
+		},
+		{
+			name:      "whole seconds",
+			timeRange: &TimeRange{Start: time.Unix(1700000000, 0), End: time.Unix(1700000060, 0)},
+			wantQuery: "end=1700000060&start=1700000000",
+		},
+		{
+			name:      "end rounded up",
+			timeRange: &TimeRange{Start: time.Unix(1700000000, 900_000_000), End: time.Unix(1700000060, 1)},
+			wantQuery: "end=1700000061&start=1700000000",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.RawQuery != tt.wantQuery {
+					t.Errorf("query = %s, want %s", r.URL.RawQuery, tt.wantQuery)
+				}
+				if got := r.Header.Get("X-Scope-OrgID"); got != "tenant" {
+					t.Errorf("X-Scope-OrgID = %s, want tenant", got)
+				}
+				w.WriteHeader(http.StatusNotFound)
+			}))
+			defer server.Close()
+
+			tempo := NewTempoBackend(&gconfig.TempoConfig{URL: server.URL, TenantID: "tenant"})
+			if _, err := tempo.SearchSpans(context.Background(), &SearchSpansRequest{TraceID: traceID, TimeRange: tt.timeRange}); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestTempoBackendSearchSpansFormats(t *testing.T) {
+	traceID, err := model.ParseTraceID("4bf92f3577b34da6a3ce929d0e0e4736")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        []byte
+		status      int
+		wantSpans   int
+		wantErr     bool
+	}{
+		{
+			name:        "protobuf",
+			contentType: "application/protobuf",
+			body:        tempoProtoTrace(t),
+			status:      http.StatusOK,
+			wantSpans:   1,
+		},
+		{
+			name:        "JSON with batches",
+			contentType: "application/json",
+			body:        []byte(fmt.Sprintf(`{"batches":%s}`, tempoJSONResourceSpans)),
+			status:      http.StatusOK,
+			wantSpans:   1,
+		},
+		{
+			name:        "JSON with resourceSpans",
+			contentType: "application/json; charset=utf-8",
+			body:        []byte(fmt.Sprintf(`{"resourceSpans":%s}`, tempoJSONResourceSpans)),
+			status:      http.StatusOK,
+			wantSpans:   1,
+		},
+		{
+			name:   "trace not found",
+			status: http.StatusNotFound,
+		},
+		{
+			name:    "server error",
+			status:  http.StatusInternalServerError,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/api/traces/4bf92f3577b34da6a3ce929d0e0e4736" {
+					t.Errorf("path = %s, want the trace endpoint", r.URL.Path)
+				}
+				if tt.contentType != "" {
+					w.Header().Set("Content-Type", tt.contentType)
+				}
+				w.WriteHeader(tt.status)
+				_, _ = w.Write(tt.body)
+			}))
+			defer server.Close()
+
+			spans, err := NewTempoBackend(&gconfig.TempoConfig{URL: server.URL}).
+				SearchSpans(context.Background(), &SearchSpansRequest{TraceID: traceID})
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("SearchSpans() = %v, want an error", spans)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(spans) != tt.wantSpans {
+				t.Fatalf("SearchSpans() returned %d spans, want %d", len(spans), tt.wantSpans)
+			}
+			for _, span := range spans {
+				if span.SpanID != "a3ce929d0e0e4736" || span.ServiceName != "frontend" || span.Duration != 250*time.Millisecond {
+					t.Errorf("span = %+v, want a3ce929d0e0e4736 of frontend running for 250ms", span)
+				}
+			}
+		})
+	}
+}
+
+func TestTempoBackendLocateTraceNotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	traceID, err := model.ParseTraceID("4bf92f3577b34da6a3ce929d0e0e4736")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = NewTempoBackend(&gconfig.TempoConfig{URL: server.URL}).
+		LocateTrace(context.Background(), &LocateTraceRequest{TraceID: traceID, Lookback: time.Hour})
+	if !errors.Is(err, ErrTraceNotFound) {
+		t.Errorf("LocateTrace() error = %v, want %v", err, ErrTraceNotFound)
+	}
+}
@@ -1,9 +1,101 @@
 package backend
 
-import "time"
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ymtdzzz/telemetry-glue/pkg/app/model"
+)
 
 // TimeRange represents a time range with start and end times
 type TimeRange struct {
 	Start time.Time
 	End   time.Time
 }
+
+// traceTimeRangePadding widens a located trace time range so that logs emitted
+// slightly before or after the spans are included
+const traceTimeRangePadding = 5 * time.Minute
+
+// spansTimeRange returns the time range from the earliest span start to the latest span end
+func spansTimeRange(spans model.Spans) (*TimeRange, error) {
+	earliest, latest := (&model.Telemetry{Spans: spans}).TimeRange()
+	if earliest.IsZero() {
+		return nil, ErrTraceNotFound
+	}
+
+	return &TimeRange{
+		Start: earliest.Add(-traceTimeRangePadding),
+		End:   latest.Add(traceTimeRangePadding),
+	}, nil
+}
+
+// locatedSpans keeps the spans downloaded to locate a trace, so that backends locating traces
+// by fetching them whole (e.g., Jaeger and Tempo) do not download the trace again
+type locatedSpans struct {
+	mu      sync.Mutex
+	traceID model.TraceID
+	spans   model.Spans
+}
+
+// locate fetches the trace within the lookback window, keeps its spans and returns its time range
+func (l *locatedSpans) locate(
+	ctx context.Context,
+	req *LocateTraceRequest,
+	fetchSpans func(context.Context, *SearchSpansRequest) (model.Spans, error),
+) (*TimeRange, error) {
+	now := time.Now()
+	spans, err := fetchSpans(ctx, &SearchSpansRequest{
+		TraceID:   req.TraceID,
+		TimeRange: &TimeRange{Start: now.Add(-req.Lookback), End: now},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	timeRange, err := spansTimeRange(spans)
+	if err != nil {
+		return nil, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.traceID, l.spans = req.TraceID, spans
+
+	return timeRange, nil
+}
+
+// load returns the located spans of the trace within the time range, if the trace was located
+func (l *locatedSpans) load(req *SearchSpansRequest) (model.Spans, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.spans == nil || l.traceID.W3C() != req.TraceID.W3C() {
+		return nil, false
+	}
+
+	var spans model.Spans
+	for _, s := range l.spans {
+		if req.TimeRange.contains(s.StartTime) {
+			spans = append(spans, s)
+		}
+	}
+	return spans, true
+}
+
+// sortLogsByTimestamp sorts logs in ascending order of timestamp
+func sortLogsByTimestamp(logs model.Logs) {
+	sort.SliceStable(logs, func(i, j int) bool {
+		return logs[i].Timestamp.Before(logs[j].Timestamp)
+	})
+}
+
+// contains reports whether t is within the time range. A nil time range contains any time.
+func (r *TimeRange) contains(t time.Time) bool {
+	if r == nil {
+		return true
+	}
+	return !t.Before(r.Start) && !t.After(r.End)
+}
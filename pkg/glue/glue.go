@@ -2,60 +2,157 @@ package glue
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
 
 	"github.com/ymtdzzz/telemetry-glue/pkg/app/config"
 	"github.com/ymtdzzz/telemetry-glue/pkg/app/model"
 	"github.com/ymtdzzz/telemetry-glue/pkg/glue/backend"
+	"golang.org/x/sync/errgroup"
 )
 
+// defaultLocateLookback is how far back a trace is searched by default when locating it
+const defaultLocateLookback = 7 * 24 * time.Hour
+
 type Glue struct {
-	spanBackend backend.GlueBackend
-	logBackend  backend.GlueBackend
+	spanBackend    backend.GlueBackend
+	logBackend     backend.GlueBackend
+	partialResults bool
+	locateLookback time.Duration
 }
 
-func NewGlue(cfg *config.GlueConfig) *Glue {
-	glue := &Glue{}
+// NewGlue creates a new Glue with the span and log backends selected in the configuration
+func NewGlue(ctx context.Context, cfg *config.GlueConfig) (*Glue, error) {
+	glue := &Glue{
+		partialResults: cfg.PartialResults,
+		locateLookback: cfg.LocateLookback,
+	}
+	if glue.locateLookback == 0 {
+		glue.locateLookback = defaultLocateLookback
+	}
+	registry := newBackendRegistry(cfg)
 
-	var nrBackend *backend.NewRelicBackend
-	if cfg.NewRelic.HasAnyConfig() {
-		nrBackend = backend.NewNewRelicBackend(&cfg.NewRelic)
+	if cfg.SpanBackend != "" {
+		b, err := registry.get(ctx, cfg.SpanBackend)
+		if err != nil {
+			return nil, err
+		}
+		glue.spanBackend = b
 	}
 
-	if cfg.SpanBackend == config.BackendTypeNewRelic {
-		glue.spanBackend = nrBackend
+	if cfg.LogBackend != "" {
+		b, err := registry.get(ctx, cfg.LogBackend)
+		if err != nil {
+			return nil, err
+		}
+		glue.logBackend = b
 	}
 
-	return glue
+	return glue, nil
+}
+
+// LocateTrace discovers the time range of the trace using the span backend, falling back to
+// the log backend when the span backend cannot locate traces or does not find the trace
+func (g *Glue) LocateTrace(ctx context.Context, traceID model.TraceID) (*backend.TimeRange, error) {
+	var located backend.GlueBackend
+	for _, b := range []backend.GlueBackend{g.spanBackend, g.logBackend} {
+		locator, ok := b.(backend.TraceLocator)
+		// A backend used for both spans and logs is not asked twice
+		if !ok || b == located {
+			continue
+		}
+		located = b
+
+		timeRange, err := locator.LocateTrace(ctx, &backend.LocateTraceRequest{
+			TraceID:  traceID,
+			Lookback: g.locateLookback,
+		})
+		if errors.Is(err, backend.ErrTraceNotFound) {
+			continue
+		}
+		return timeRange, err
+	}
+	if located != nil {
+		return nil, backend.ErrTraceNotFound
+	}
+	return nil, errors.New("the configured backends cannot locate traces; specify the start time explicitly")
 }
 
+// Execute fetches spans and logs concurrently. The first error cancels the other fetch,
+// except in partial-result mode where a failed log fetch is recorded as a warning instead.
 func (g *Glue) Execute(
 	ctx context.Context,
-	traceID string,
+	traceID model.TraceID,
 	spanReq *backend.SearchSpansRequest,
 	logReq *backend.SearchLogsRequest,
 ) (*model.Telemetry, error) {
 	var (
-		spans model.Spans
-		logs  model.Logs
-		err   error
+		spans    model.Spans
+		logs     model.Logs
+		warnings []model.Warning
+		mu       sync.Mutex
 	)
 
+	eg, ctx := errgroup.WithContext(ctx)
+
 	if g.spanBackend != nil {
-		spans, err = g.spanBackend.SearchSpans(ctx, spanReq)
-		if err != nil {
-			return nil, err
-		}
+		eg.Go(func() error {
+			var err error
+			spans, err = g.spanBackend.SearchSpans(ctx, spanReq)
+			if w, ok := truncationWarning("spans", err); ok {
+				mu.Lock()
+				warnings = append(warnings, w)
+				mu.Unlock()
+				return nil
+			}
+			return err
+		})
 	}
 
 	if g.logBackend != nil {
-		logs, err = g.logBackend.SearchLogs(ctx, logReq)
-		if err != nil {
-			return nil, err
-		}
+		eg.Go(func() error {
+			var err error
+			logs, err = g.logBackend.SearchLogs(ctx, logReq)
+			if w, ok := truncationWarning("logs", err); ok {
+				mu.Lock()
+				warnings = append(warnings, w)
+				mu.Unlock()
+				return nil
+			}
+			if err != nil && g.partialResults {
+				mu.Lock()
+				warnings = append(warnings, model.Warning{
+					Source:  "logs",
+					Message: "failed to fetch logs: " + err.Error(),
+				})
+				mu.Unlock()
+				return nil
+			}
+			return err
+		})
+	}
+
+	if err := eg.Wait(); err != nil {
+		return nil, err
 	}
 
 	return &model.Telemetry{
-		Spans: spans,
-		Logs:  logs,
+		Spans:    spans,
+		Logs:     logs,
+		Warnings: warnings,
 	}, nil
 }
+
+// truncationWarning converts a TruncatedError into a warning as the partial result is still usable
+func truncationWarning(source string, err error) (model.Warning, bool) {
+	var truncErr *backend.TruncatedError
+	if !errors.As(err, &truncErr) {
+		return model.Warning{}, false
+	}
+	return model.Warning{
+		Source:  source,
+		Message: fmt.Sprintf("only the first %d %s were fetched; the rest of the trace is missing", truncErr.Limit, source),
+	}, true
+}
@@ -0,0 +1,270 @@
+package glue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ymtdzzz/telemetry-glue/pkg/app/model"
+	"github.com/ymtdzzz/telemetry-glue/pkg/glue/backend"
+)
+
+// fakeBackend is a backend that only supports locating traces
+type fakeBackend struct {
+	timeRange *backend.TimeRange
+	err       error
+	calls     int
+}
+
+func (f *fakeBackend) SearchSpans(ctx context.Context, req *backend.SearchSpansRequest) (model.Spans, error) {
+	return nil, nil
+}
+
+func (f *fakeBackend) SearchLogs(ctx context.Context, req *backend.SearchLogsRequest) (model.Logs, error) {
+	return nil, nil
+}
+
+func (f *fakeBackend) LocateTrace(ctx context.Context, req *backend.LocateTraceRequest) (*backend.TimeRange, error) {
+	f.calls++
+	return f.timeRange, f.err
+}
+
+// nonLocatingBackend is a backend without the TraceLocator capability
+type nonLocatingBackend struct{}
+
+func (nonLocatingBackend) SearchSpans(ctx context.Context, req *backend.SearchSpansRequest) (model.Spans, error) {
+	return nil, nil
+}
+
+func (nonLocatingBackend) SearchLogs(ctx context.Context, req *backend.SearchLogsRequest) (model.Logs, error) {
+	return nil, nil
+}
+
+// fetchBackend is a backend returning fixed spans and logs. When block is set, the searches
+// wait for the context to be canceled instead.
+type fetchBackend struct {
+	spans    model.Spans
+	logs     model.Logs
+	err      error
+	block    bool
+	canceled bool
+}
+
+func (f *fetchBackend) SearchSpans(ctx context.Context, req *backend.SearchSpansRequest) (model.Spans, error) {
+	if err := f.wait(ctx); err != nil {
+		return nil, err
+	}
+	return f.spans, f.err
+}
+
+func (f *fetchBackend) SearchLogs(ctx context.Context, req *backend.SearchLogsRequest) (model.Logs, error) {
+	if err := f.wait(ctx); err != nil {
+		return nil, err
+	}
+	return f.logs, f.err
+}
+
+func (f *fetchBackend) wait(ctx context.Context) error {
+	if !f.block {
+		return nil
+	}
+	<-ctx.Done()
+	f.canceled = true
+	return ctx.Err()
+}
+
+func TestGlueExecute(t *testing.T) {
+	spans := model.Spans{{SpanID: "a"}, {SpanID: "b"}}
+	logs := model.Logs{{Message: "log"}}
+	failure := errors.New("backend unavailable")
+
+	tests := []struct {
+		name           string
+		spanBackend    *fetchBackend
+		logBackend     *fetchBackend
+		partialResults bool
+		wantSpans      int
+		wantLogs       int
+		wantWarnings   []model.Warning
+		wantErr        error
+	}{
+		{
+			name:        "spans and logs",
+			spanBackend: &fetchBackend{spans: spans},
+			logBackend:  &fetchBackend{logs: logs},
+			wantSpans:   2,
+			wantLogs:    1,
+		},
+		{
+			name:        "span error cancels the log fetch",
+			spanBackend: &fetchBackend{err: failure},
+			logBackend:  &fetchBackend{block: true},
+			wantErr:     failure,
+		},
+		{
+			name:        "log error cancels the span fetch",
+			spanBackend: &fetchBackend{block: true},
+			logBackend:  &fetchBackend{err: failure},
+			wantErr:     failure,
+		},
+		{
+			name:           "log error as a warning with partial results",
+			spanBackend:    &fetchBackend{spans: spans},
+			logBackend:     &fetchBackend{err: failure},
+			partialResults: true,
+			wantSpans:      2,
+			wantWarnings:   []model.Warning{{Source: "logs", Message: "failed to fetch logs: backend unavailable"}},
+		},
+		{
+			name:           "span error with partial results",
+			spanBackend:    &fetchBackend{err: failure},
+			logBackend:     &fetchBackend{logs: logs},
+			partialResults: true,
+			wantErr:        failure,
+		},
+		{
+			name:         "truncated spans",
+			spanBackend:  &fetchBackend{spans: spans, err: &backend.TruncatedError{Limit: 2}},
+			logBackend:   &fetchBackend{logs: logs},
+			wantSpans:    2,
+			wantLogs:     1,
+			wantWarnings: []model.Warning{{Source: "spans", Message: "only the first 2 spans were fetched; the rest of the trace is missing"}},
+		},
+		{
+			name:         "truncated logs",
+			spanBackend:  &fetchBackend{spans: spans},
+			logBackend:   &fetchBackend{logs: logs, err: fmt.Errorf("page 3: %w", &backend.TruncatedError{Limit: 1})},
+			wantSpans:    2,
+			wantLogs:     1,
+			wantWarnings: []model.Warning{{Source: "logs", Message: "only the first 1 logs were fetched; the rest of the trace is missing"}},
+		},
+		{
+			name:       "no span backend",
+			logBackend: &fetchBackend{logs: logs},
+			wantLogs:   1,
+		},
+		{
+			name:        "no log backend",
+			spanBackend: &fetchBackend{spans: spans},
+			wantSpans:   2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &Glue{partialResults: tt.partialResults}
+			// Assigned only when set, as a nil *fetchBackend is not a nil backend
+			if tt.spanBackend != nil {
+				g.spanBackend = tt.spanBackend
+			}
+			if tt.logBackend != nil {
+				g.logBackend = tt.logBackend
+			}
+
+			got, err := g.Execute(context.Background(), model.TraceID{}, &backend.SearchSpansRequest{}, &backend.SearchLogsRequest{})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Execute() error = %v, want %v", err, tt.wantErr)
+				}
+				for _, b := range []*fetchBackend{tt.spanBackend, tt.logBackend} {
+					if b.block && !b.canceled {
+						t.Error("Execute() did not cancel the other fetch")
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got.Spans) != tt.wantSpans {
+				t.Errorf("Execute() returned %d spans, want %d", len(got.Spans), tt.wantSpans)
+			}
+			if len(got.Logs) != tt.wantLogs {
+				t.Errorf("Execute() returned %d logs, want %d", len(got.Logs), tt.wantLogs)
+			}
+			if !reflect.DeepEqual(got.Warnings, tt.wantWarnings) {
+				t.Errorf("Execute() warnings = %v, want %v", got.Warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestGlueLocateTrace(t *testing.T) {
+	spanRange := &backend.TimeRange{Start: time.Unix(1700000000, 0), End: time.Unix(1700000600, 0)}
+	logRange := &backend.TimeRange{Start: time.Unix(1700001000, 0), End: time.Unix(1700001600, 0)}
+	failure := errors.New("backend unavailable")
+	shared := &fakeBackend{err: backend.ErrTraceNotFound}
+
+	tests := []struct {
+		name        string
+		spanBackend backend.GlueBackend
+		logBackend  backend.GlueBackend
+		want        *backend.TimeRange
+		wantErr     error
+		wantCalls   map[*fakeBackend]int
+	}{
+		{
+			name:        "found by the span backend",
+			spanBackend: &fakeBackend{timeRange: spanRange},
+			logBackend:  &fakeBackend{timeRange: logRange},
+			want:        spanRange,
+		},
+		{
+			name:        "falls back to the log backend when not found",
+			spanBackend: &fakeBackend{err: backend.ErrTraceNotFound},
+			logBackend:  &fakeBackend{timeRange: logRange},
+			want:        logRange,
+		},
+		{
+			name:        "falls back to the log backend when the span backend cannot locate",
+			spanBackend: nonLocatingBackend{},
+			logBackend:  &fakeBackend{timeRange: logRange},
+			want:        logRange,
+		},
+		{
+			name:        "other errors are returned",
+			spanBackend: &fakeBackend{err: failure},
+			logBackend:  &fakeBackend{timeRange: logRange},
+			wantErr:     failure,
+		},
+		{
+			name:        "not found by any backend",
+			spanBackend: &fakeBackend{err: backend.ErrTraceNotFound},
+			logBackend:  &fakeBackend{err: backend.ErrTraceNotFound},
+			wantErr:     backend.ErrTraceNotFound,
+		},
+		{
+			name:        "shared backend is asked once",
+			spanBackend: shared,
+			logBackend:  shared,
+			wantErr:     backend.ErrTraceNotFound,
+			wantCalls:   map[*fakeBackend]int{shared: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &Glue{spanBackend: tt.spanBackend, logBackend: tt.logBackend, locateLookback: time.Hour}
+			got, err := g.LocateTrace(context.Background(), model.TraceID{})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("LocateTrace() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("LocateTrace() = %v, want %v", got, tt.want)
+			}
+			for b, calls := range tt.wantCalls {
+				if b.calls != calls {
+					t.Errorf("LocateTrace() called the backend %d times, want %d", b.calls, calls)
+				}
+			}
+		})
+	}
+
+	g := &Glue{spanBackend: nonLocatingBackend{}, locateLookback: time.Hour}
+	if _, err := g.LocateTrace(context.Background(), model.TraceID{}); err == nil || errors.Is(err, backend.ErrTraceNotFound) {
+		t.Errorf("LocateTrace() error = %v, want an error asking for the start time", err)
+	}
+}
@@ -0,0 +1,71 @@
+package glue
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ymtdzzz/telemetry-glue/pkg/app/config"
+	"github.com/ymtdzzz/telemetry-glue/pkg/glue/backend"
+)
+
+// backendFactory builds a GlueBackend from the glue configuration
+type backendFactory func(ctx context.Context, cfg *config.GlueConfig) (backend.GlueBackend, error)
+
+// backendFactories holds the factory for each supported backend type
+var backendFactories = map[config.BackendType]backendFactory{
+	config.BackendTypeNewRelic: func(_ context.Context, cfg *config.GlueConfig) (backend.GlueBackend, error) {
+		return backend.NewNewRelicBackend(&cfg.NewRelic), nil
+	},
+	config.BackendTypeGCP: func(ctx context.Context, cfg *config.GlueConfig) (backend.GlueBackend, error) {
+		return backend.NewGCPBackend(ctx, &cfg.GCP)
+	},
+	config.BackendTypeJaeger: func(_ context.Context, cfg *config.GlueConfig) (backend.GlueBackend, error) {
+		return backend.NewJaegerBackend(&cfg.Jaeger), nil
+	},
+	config.BackendTypeTempo: func(_ context.Context, cfg *config.GlueConfig) (backend.GlueBackend, error) {
+		return backend.NewTempoBackend(&cfg.Tempo), nil
+	},
+	config.BackendTypeLoki: func(_ context.Context, cfg *config.GlueConfig) (backend.GlueBackend, error) {
+		return backend.NewLokiBackend(&cfg.Loki)
+	},
+	config.BackendTypeElasticsearch: func(_ context.Context, cfg *config.GlueConfig) (backend.GlueBackend, error) {
+		return backend.NewElasticsearchBackend(&cfg.Elasticsearch), nil
+	},
+	config.BackendTypeFile: func(_ context.Context, cfg *config.GlueConfig) (backend.GlueBackend, error) {
+		return backend.NewFileBackend(&cfg.File), nil
+	},
+}
+
+// backendRegistry builds backends on demand and caches them so that
+// each backend type is instantiated only once
+type backendRegistry struct {
+	cfg      *config.GlueConfig
+	backends map[config.BackendType]backend.GlueBackend
+}
+
+func newBackendRegistry(cfg *config.GlueConfig) *backendRegistry {
+	return &backendRegistry{
+		cfg:      cfg,
+		backends: make(map[config.BackendType]backend.GlueBackend),
+	}
+}
+
+// get returns the backend for the given type, building it on first use
+func (r *backendRegistry) get(ctx context.Context, backendType config.BackendType) (backend.GlueBackend, error) {
+	if b, ok := r.backends[backendType]; ok {
+		return b, nil
+	}
+
+	factory, ok := backendFactories[backendType]
+	if !ok {
+		return nil, fmt.Errorf("unsupported backend type: %s", backendType)
+	}
+
+	b, err := factory(ctx, r.cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s backend: %w", backendType, err)
+	}
+	r.backends[backendType] = b
+
+	return b, nil
+}
@@ -0,0 +1,110 @@
+package glue
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ymtdzzz/telemetry-glue/pkg/app/config"
+	"github.com/ymtdzzz/telemetry-glue/pkg/glue/backend"
+)
+
+// registerFakeFactory registers a factory for the backend type for the duration of the test
+// and returns the number of times it was called
+func registerFakeFactory(t *testing.T, backendType config.BackendType, err error) *int {
+	t.Helper()
+	calls := new(int)
+	backendFactories[backendType] = func(_ context.Context, _ *config.GlueConfig) (backend.GlueBackend, error) {
+		*calls++
+		if err != nil {
+			return nil, err
+		}
+		return &fetchBackend{}, nil
+	}
+	t.Cleanup(func() { delete(backendFactories, backendType) })
+	return calls
+}
+
+func TestNewGlueSharedBackend(t *testing.T) {
+	calls := registerFakeFactory(t, "fake", nil)
+
+	g, err := NewGlue(context.Background(), &config.GlueConfig{SpanBackend: "fake", LogBackend: "fake"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.spanBackend == nil || g.spanBackend != g.logBackend {
+		t.Errorf("span backend %p and log backend %p, want the same instance", g.spanBackend, g.logBackend)
+	}
+	if *calls != 1 {
+		t.Errorf("factory called %d times, want 1", *calls)
+	}
+}
+
+func TestBackendRegistryGet(t *testing.T) {
+	failure := errors.New("missing credentials")
+
+	tests := []struct {
+		name      string
+		types     []config.BackendType
+		wantCalls map[config.BackendType]int
+		wantErr   string
+		wantCause error
+	}{
+		{
+			name:      "each factory called once",
+			types:     []config.BackendType{"fake", "other", "fake", "other"},
+			wantCalls: map[config.BackendType]int{"fake": 1, "other": 1},
+		},
+		{
+			name:    "unknown type",
+			types:   []config.BackendType{"unknown"},
+			wantErr: "unsupported backend type: unknown",
+		},
+		{
+			name:      "factory error",
+			types:     []config.BackendType{"broken"},
+			wantCalls: map[config.BackendType]int{"broken": 1},
+			wantErr:   "failed to create broken backend: missing credentials",
+			wantCause: failure,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := map[config.BackendType]*int{
+				"fake":   registerFakeFactory(t, "fake", nil),
+				"other":  registerFakeFactory(t, "other", nil),
+				"broken": registerFakeFactory(t, "broken", failure),
+			}
+			registry := newBackendRegistry(&config.GlueConfig{})
+
+			var err error
+			got := map[config.BackendType]backend.GlueBackend{}
+			for _, backendType := range tt.types {
+				var b backend.GlueBackend
+				if b, err = registry.get(context.Background(), backendType); err != nil {
+					break
+				}
+				if prev, ok := got[backendType]; ok && prev != b {
+					t.Errorf("get(%s) returned a new instance, want the cached one", backendType)
+				}
+				got[backendType] = b
+			}
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("get() error = %v, want %q", err, tt.wantErr)
+				}
+				if tt.wantCause != nil && !errors.Is(err, tt.wantCause) {
+					t.Errorf("get() error = %v, want it to wrap %v", err, tt.wantCause)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			for backendType, want := range tt.wantCalls {
+				if got := *calls[backendType]; got != want {
+					t.Errorf("%s factory called %d times, want %d", backendType, got, want)
+				}
+			}
+		})
+	}
+}